@@ -5,19 +5,19 @@ provider "hedvig" {
 }
 
 resource "hedvig_vdisk" "my-vdisk-lumosBlock55" {
-  name = "HedvigVdiskLumosBlock55"
-#  clusteredfilesystem = "true"
-  deduplication = "true"
-  scsi3pr = "false"
-  compressed = "true"
-  encryption = "false"
+  name = "vdisk-lumos-block-55"
+#  clusteredfilesystem = true
+  deduplication = true
+  scsi3pr = false
+  compressed = true
+  encryption = false
 #  description = "Stuff about this Vdisk."
   residence = "HDD"
   type = "Block"
   size = 7
   blocksize = "4096"
   replicationpolicy = "Agnostic"
-  cacheenabled = "true"
+  cacheenabled = true
 }
 
 #resource "hedvig_access" "my-access-fudgeFlash23" {
@@ -2,4 +2,7 @@ module github.com/terraform-providers/terraform-provider-hedvig
 
 go 1.12
 
-require github.com/hashicorp/terraform v0.12.8
+require (
+	github.com/hashicorp/go-cleanhttp v0.5.0
+	github.com/hashicorp/terraform v0.12.8
+)
@@ -0,0 +1,119 @@
+package client
+
+import "context"
+
+type PersistACLAccess struct {
+	VirtualDisks []string `json:"virtualDisks"`
+	Host         string   `json:"host"`
+	Address      string   `json:"address"`
+	AccessType   string   `json:"type"`
+}
+
+func (PersistACLAccess) Type() string     { return "PersistACLAccess" }
+func (PersistACLAccess) Category() string { return categoryVirtualDisk }
+
+type PersistACLAccessResponse struct {
+	Response
+	Result []DiskResult `json:"result"`
+}
+
+func (c *Client) PersistACLAccess(ctx context.Context, req *PersistACLAccess) (*PersistACLAccessResponse, error) {
+	resp := &PersistACLAccessResponse{}
+	err := c.mutate(ctx, req, resp, func() (bool, error) {
+		for _, name := range req.VirtualDisks {
+			acl, err := c.GetACLInformation(ctx, name)
+			if err != nil {
+				return false, err
+			}
+			if !acl.Allows(req.Host, req.Address) {
+				return false, nil
+			}
+		}
+		*resp = PersistACLAccessResponse{Response: appliedResponse(req), Result: appliedDisks(req.VirtualDisks...)}
+		return true, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp, checkDiskResults(&resp.Response, req, resp.Result)
+}
+
+type GetACLInformation struct {
+	VirtualDisk string `json:"virtualDisk"`
+}
+
+func (GetACLInformation) Type() string     { return "GetACLInformation" }
+func (GetACLInformation) Category() string { return categoryVirtualDisk }
+
+// ACL lists the initiators of a host that may access a vdisk.
+type ACL struct {
+	Host      string `json:"host"`
+	Initiator []struct {
+		IP   string `json:"ip"`
+		Name string `json:"name"`
+	} `json:"initiator"`
+}
+
+type GetACLInformationResponse struct {
+	Response
+	Result []ACL `json:"result"`
+}
+
+// Allows reports whether address is listed as an initiator of host.
+func (r *GetACLInformationResponse) Allows(host, address string) bool {
+	for _, rec := range r.Result {
+		if rec.Host != host {
+			continue
+		}
+		for _, initiator := range rec.Initiator {
+			if initiator.IP == address {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (c *Client) GetACLInformation(ctx context.Context, vdisk string) (*GetACLInformationResponse, error) {
+	resp := &GetACLInformationResponse{}
+	if err := c.read(ctx, &GetACLInformation{VirtualDisk: vdisk}, resp); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+type RemoveACLAccess struct {
+	VirtualDisk string   `json:"virtualDisk"`
+	Host        string   `json:"host"`
+	Address     []string `json:"address"`
+}
+
+func (RemoveACLAccess) Type() string     { return "RemoveACLAccess" }
+func (RemoveACLAccess) Category() string { return categoryVirtualDisk }
+
+type RemoveACLAccessResponse struct {
+	Response
+}
+
+func (c *Client) RemoveACLAccess(ctx context.Context, req *RemoveACLAccess) (*RemoveACLAccessResponse, error) {
+	resp := &RemoveACLAccessResponse{}
+	err := c.mutate(ctx, req, resp, func() (bool, error) {
+		acl, err := c.GetACLInformation(ctx, req.VirtualDisk)
+		if err != nil && !IsNotFound(err) {
+			return false, err
+		}
+		if err == nil {
+			for _, address := range req.Address {
+				if acl.Allows(req.Host, address) {
+					return false, nil
+				}
+			}
+		}
+		*resp = RemoveACLAccessResponse{Response: appliedResponse(req)}
+		return true, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
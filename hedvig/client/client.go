@@ -0,0 +1,236 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// Client talks to the REST API served by the nodes of a Hedvig cluster.
+type Client struct {
+	Username string
+	Password string
+	// Nodes are the cluster nodes whose REST API may be used. Requests go to
+	// the last node known to be healthy.
+	Nodes []string
+	// Scheme is either http or https.
+	Scheme string
+
+	HTTPClient *http.Client
+	Retry      RetryPolicy
+
+	mu        sync.Mutex
+	sessionID string
+
+	nodeMu  sync.Mutex
+	current int
+}
+
+// Request is implemented by the params of every Hedvig API call.
+type Request interface {
+	// Type is the API type, e.g. AddVirtualDisk.
+	Type() string
+	// Category is the API category, e.g. VirtualDiskManagement.
+	Category() string
+}
+
+// Response holds the fields shared by every Hedvig API response.
+type Response struct {
+	RequestID string `json:"requestId"`
+	Type      string `json:"type"`
+	Status    string `json:"status"`
+	Message   string `json:"message"`
+}
+
+func (r *Response) response() *Response { return r }
+
+type apiResponse interface {
+	response() *Response
+}
+
+// appliedResponse stands in for the response of a mutation that was found
+// to have taken effect although its call failed.
+func appliedResponse(req Request) Response {
+	return Response{Type: req.Type(), Status: "ok"}
+}
+
+type envelope struct {
+	Type      string  `json:"type"`
+	Category  string  `json:"category"`
+	Params    Request `json:"params,omitempty"`
+	SessionID string  `json:"sessionId,omitempty"`
+}
+
+func New(nodes []string, username, password string) *Client {
+	return &Client{
+		Username:   username,
+		Password:   password,
+		Nodes:      nodes,
+		Scheme:     "http",
+		HTTPClient: http.DefaultClient,
+		Retry:      DefaultRetryPolicy(),
+	}
+}
+
+type loginParams struct {
+	UserName string `json:"userName"`
+	Password string `json:"password"`
+	Cluster  string `json:"cluster"`
+}
+
+func (loginParams) Type() string     { return "Login" }
+func (loginParams) Category() string { return "UserManagement" }
+
+type LoginResponse struct {
+	Response
+	Result struct {
+		Datacenters []interface{} `json:"datacenters"`
+		DisplayName string        `json:"displayName"`
+		Roles       struct {
+			Hedvig string `json:"Hedvig"`
+		} `json:"roles"`
+		Dualdc        bool   `json:"dualdc"`
+		SessionID     string `json:"sessionId"`
+		UserName      string `json:"userName"`
+		PrimaryTenant string `json:"primaryTenant"`
+	} `json:"result"`
+}
+
+// Login authenticates against the cluster and returns a new session ID.
+func (c *Client) Login(ctx context.Context) (string, error) {
+	req := &loginParams{UserName: c.Username, Password: c.Password}
+	login := LoginResponse{}
+	err := c.retry(ctx, req, &login, func() error { return c.do(ctx, req, "", &login) }, nil)
+	if err != nil {
+		log.Printf("[ERROR] Login to %s failed: %s", c.node(), err)
+		return "", err
+	}
+
+	return login.Result.SessionID, nil
+}
+
+// call performs an authenticated API call and decodes the response into out.
+// The session is shared by all callers and renewed once if the cluster
+// reports it as expired.
+func (c *Client) call(ctx context.Context, req Request, out interface{}) error {
+	sessionID, err := c.session(ctx)
+	if err != nil {
+		return err
+	}
+
+	err = c.do(ctx, req, sessionID, out)
+	if !IsAuth(err) {
+		return err
+	}
+
+	log.Printf("[DEBUG] Hedvig session expired, logging in to %s again", c.node())
+	c.invalidate(sessionID)
+
+	sessionID, err = c.session(ctx)
+	if err != nil {
+		return err
+	}
+
+	return c.do(ctx, req, sessionID, out)
+}
+
+func (c *Client) do(ctx context.Context, req Request, sessionID string, out interface{}) error {
+	raw, err := json.Marshal(envelope{
+		Type:      req.Type(),
+		Category:  req.Category(),
+		Params:    req,
+		SessionID: sessionID,
+	})
+	if err != nil {
+		return err
+	}
+
+	q := url.Values{}
+	q.Set("request", string(raw))
+
+	node := c.node()
+	log.Printf("[DEBUG] Hedvig request %s to %s", req.Type(), node)
+
+	// A failed node is reported to retry, which decides whether the request
+	// may be sent to another node.
+	resp, err := c.get(ctx, node, q)
+	if err != nil {
+		return &nodeError{Node: node, Err: err}
+	}
+	defer resp.Body.Close()
+
+	if nodeFailed(resp) {
+		return &nodeError{Node: node, Err: &HedvigAPIError{HTTPStatus: resp.StatusCode, RequestType: req.Type()}}
+	}
+
+	if resp.StatusCode == http.StatusNotFound {
+		return &HedvigAPIError{
+			HTTPStatus:  resp.StatusCode,
+			Message:     "Malformed query; aborting",
+			RequestType: req.Type(),
+		}
+	}
+
+	if resp.StatusCode >= 400 {
+		return &HedvigAPIError{HTTPStatus: resp.StatusCode, RequestType: req.Type()}
+	}
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	// out may be reused when a call is retried; start from a clean value.
+	v := reflect.ValueOf(out).Elem()
+	v.Set(reflect.Zero(v.Type()))
+
+	if err := json.Unmarshal(body, out); err != nil {
+		return fmt.Errorf("Error decoding %s response: %s", req.Type(), err)
+	}
+
+	if r, ok := out.(apiResponse); ok {
+		if status := r.response().Status; status != "" && status != "ok" {
+			return &HedvigAPIError{
+				HTTPStatus:  resp.StatusCode,
+				Status:      status,
+				Message:     r.response().Message,
+				RequestID:   r.response().RequestID,
+				RequestType: req.Type(),
+			}
+		}
+	}
+
+	return nil
+}
+
+// mutationKey marks the context of a call that changes cluster state.
+type mutationKey struct{}
+
+func (c *Client) get(ctx context.Context, node string, q url.Values) (*http.Response, error) {
+	u := url.URL{}
+	u.Host = node
+	u.Path = "/rest/"
+	u.Scheme = c.Scheme
+	u.RawQuery = q.Encode()
+
+	req, err := http.NewRequest("GET", u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	// The transport sends a GET again by itself when a reused connection
+	// breaks before the answer arrives. An empty body marks a mutation as
+	// not replayable, leaving the decision to retry.
+	if mutation, _ := ctx.Value(mutationKey{}).(bool); mutation {
+		req.Body = ioutil.NopCloser(strings.NewReader(""))
+	}
+
+	return c.HTTPClient.Do(req.WithContext(ctx))
+}
@@ -0,0 +1,147 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+func TestClientEscapesRequestParams(t *testing.T) {
+	var got envelope
+	var params AddVirtualDisk
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		raw := r.URL.Query().Get("request")
+		var env struct {
+			Type      string          `json:"type"`
+			Category  string          `json:"category"`
+			Params    json.RawMessage `json:"params"`
+			SessionID string          `json:"sessionId"`
+		}
+		if err := json.Unmarshal([]byte(raw), &env); err != nil {
+			t.Fatalf("request is not valid JSON: %s: %q", err, raw)
+		}
+		got = envelope{Type: env.Type, Category: env.Category, SessionID: env.SessionID}
+
+		switch env.Type {
+		case "Login":
+			fmt.Fprint(w, `{"status":"ok","result":{"sessionId":"abc"}}`)
+		case "AddVirtualDisk":
+			if err := json.Unmarshal(env.Params, &params); err != nil {
+				t.Fatal(err)
+			}
+			fmt.Fprint(w, `{"status":"ok","result":[{"name":"d","status":"ok"}]}`)
+		default:
+			t.Fatalf("unexpected request type %q", env.Type)
+		}
+	}))
+	defer ts.Close()
+
+	u, _ := url.Parse(ts.URL)
+	c := New([]string{u.Host}, "admin", "pass'word")
+
+	desc := `it's a "quoted", {braced} description`
+	resp, err := c.AddVirtualDisk(context.Background(), &AddVirtualDisk{
+		Name:        "disk'1",
+		Size:        Size{Unit: "GB", Value: 10},
+		Description: desc,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got.Type != "AddVirtualDisk" || got.Category != "VirtualDiskManagement" || got.SessionID != "abc" {
+		t.Fatalf("unexpected envelope: %#v", got)
+	}
+	if params.Name != "disk'1" || params.Description != desc {
+		t.Fatalf("params were not round-tripped: %#v", params)
+	}
+	if len(resp.Result) != 1 || resp.Result[0].Status != "ok" {
+		t.Fatalf("unexpected response: %#v", resp)
+	}
+}
+
+func TestClientMalformedQuery(t *testing.T) {
+	ts := httptest.NewServer(http.NotFoundHandler())
+	defer ts.Close()
+
+	u, _ := url.Parse(ts.URL)
+	c := New([]string{u.Host}, "admin", "admin")
+
+	if _, err := c.Login(context.Background()); err == nil {
+		t.Fatal("expected an error for a 404 response")
+	}
+}
+
+func TestClientReusesSession(t *testing.T) {
+	var logins, expired int32
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var env struct {
+			Type      string `json:"type"`
+			SessionID string `json:"sessionId"`
+		}
+		if err := json.Unmarshal([]byte(r.URL.Query().Get("request")), &env); err != nil {
+			t.Fatal(err)
+		}
+
+		switch env.Type {
+		case "Login":
+			n := atomic.AddInt32(&logins, 1)
+			fmt.Fprintf(w, `{"status":"ok","result":{"sessionId":"s%d"}}`, n)
+		case "Logout":
+			fmt.Fprint(w, `{"status":"ok"}`)
+		case "VirtualDiskDetails":
+			if env.SessionID == "s1" && atomic.LoadInt32(&expired) == 1 {
+				fmt.Fprint(w, `{"status":"error","message":"Invalid session id"}`)
+				return
+			}
+			fmt.Fprint(w, `{"status":"ok","result":{"vDiskName":"d"}}`)
+		}
+	}))
+	defer ts.Close()
+
+	u, _ := url.Parse(ts.URL)
+	c := New([]string{u.Host}, "admin", "admin")
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := c.VirtualDiskDetails(context.Background(), "d"); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if logins != 1 {
+		t.Fatalf("expected a single login, got %d", logins)
+	}
+
+	atomic.StoreInt32(&expired, 1)
+	resp, err := c.VirtualDiskDetails(context.Background(), "d")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Status != "ok" || resp.Message != "" {
+		t.Fatalf("expected the call to be replayed after login, got %#v", resp)
+	}
+	if logins != 2 {
+		t.Fatalf("expected a second login after the session expired, got %d", logins)
+	}
+
+	if err := c.Logout(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if c.sessionID != "" {
+		t.Fatal("expected the session to be cleared on logout")
+	}
+}
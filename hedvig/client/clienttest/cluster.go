@@ -0,0 +1,40 @@
+package clienttest
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+
+	"github.com/terraform-providers/terraform-provider-hedvig/hedvig/client"
+)
+
+func init() {
+	handlers["ClusterCapacity"] = clusterCapacity
+}
+
+// clusterCapacity reports each tier of Capacity in GB, counting every
+// replica of the disks residing on it as used.
+func clusterCapacity(s *Server, params json.RawMessage) (interface{}, error) {
+	residences := []string{}
+	for residence := range s.Capacity {
+		residences = append(residences, residence)
+	}
+	sort.Strings(residences)
+
+	gb := client.UnitBytes("GB")
+	tiers := []client.TierCapacity{}
+	for _, residence := range residences {
+		var used int64
+		for _, vdisk := range s.VDisks {
+			if strings.EqualFold(vdisk.Residence, residence) {
+				used += vdisk.Size.Bytes() * int64(vdisk.ReplicationFactor)
+			}
+		}
+		tiers = append(tiers, client.TierCapacity{
+			Residence: residence,
+			Total:     client.ReportedSize{Units: "GB", Value: int(s.Capacity[residence] / gb)},
+			Used:      client.ReportedSize{Units: "GB", Value: int(used / gb)},
+		})
+	}
+	return ok(tiers), nil
+}
@@ -0,0 +1,187 @@
+package clienttest
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/terraform-providers/terraform-provider-hedvig/hedvig/client"
+)
+
+func init() {
+	handlers["AddLun"] = addLun
+	handlers["UnmapLun"] = unmapLun
+	handlers["Mount"] = mount
+	handlers["Unmount"] = unmount
+	handlers["ListExportedTargets"] = listExportedTargets
+	handlers["ListTargets"] = listTargets
+	handlers["PersistACLAccess"] = persistACLAccess
+	handlers["GetACLInformation"] = getACLInformation
+	handlers["RemoveACLAccess"] = removeACLAccess
+}
+
+func addLun(s *Server, params json.RawMessage) (interface{}, error) {
+	var p client.AddLun
+	if err := json.Unmarshal(params, &p); err != nil {
+		return nil, err
+	}
+
+	results := []interface{}{}
+	for _, name := range p.VirtualDisks {
+		vdisk, found := s.VDisks[name]
+		if !found {
+			return notFound(name), nil
+		}
+
+		targets := []interface{}{}
+		for _, target := range p.Targets {
+			if !s.hasTarget("iscsi", target) {
+				targets = append(targets, diskResult(target, "error", fmt.Sprintf("Unknown controller %s", target)))
+				continue
+			}
+			if !contains(vdisk.Luns, target) {
+				vdisk.Luns = append(vdisk.Luns, target)
+			}
+			targets = append(targets, diskResult(target, "ok", ""))
+		}
+		results = append(results, map[string]interface{}{"name": name, "status": "ok", "targets": targets})
+	}
+	return ok(results), nil
+}
+
+func unmapLun(s *Server, params json.RawMessage) (interface{}, error) {
+	var p client.UnmapLun
+	if err := json.Unmarshal(params, &p); err != nil {
+		return nil, err
+	}
+
+	vdisk, found := s.VDisks[p.VirtualDisk]
+	if !found {
+		return notFound(p.VirtualDisk), nil
+	}
+	vdisk.Luns = remove(vdisk.Luns, p.Target)
+	return ok(nil), nil
+}
+
+func mount(s *Server, params json.RawMessage) (interface{}, error) {
+	var p client.Mount
+	if err := json.Unmarshal(params, &p); err != nil {
+		return nil, err
+	}
+
+	vdisk, found := s.VDisks[p.VirtualDisk]
+	if !found {
+		return notFound(p.VirtualDisk), nil
+	}
+
+	info := []interface{}{}
+	for _, target := range p.Targets {
+		if !s.hasTarget("nfs", target) {
+			info = append(info, map[string]interface{}{
+				"target":  target,
+				"status":  "error",
+				"message": fmt.Sprintf("Exception trying to get handle to %s", target),
+			})
+			continue
+		}
+		if !contains(vdisk.Exports, target) {
+			vdisk.Exports = append(vdisk.Exports, target)
+		}
+		info = append(info, map[string]interface{}{"target": target, "status": "ok"})
+	}
+	return ok(map[string]interface{}{"exportInfo": info}), nil
+}
+
+func unmount(s *Server, params json.RawMessage) (interface{}, error) {
+	var p client.Unmount
+	if err := json.Unmarshal(params, &p); err != nil {
+		return nil, err
+	}
+
+	vdisk, found := s.VDisks[p.VirtualDisk]
+	if !found {
+		return notFound(p.VirtualDisk), nil
+	}
+	for _, target := range p.Targets {
+		vdisk.Exports = remove(vdisk.Exports, target)
+	}
+	return ok([]interface{}{diskResult(p.VirtualDisk, "ok", "")}), nil
+}
+
+func listExportedTargets(s *Server, params json.RawMessage) (interface{}, error) {
+	var p client.ListExportedTargets
+	if err := json.Unmarshal(params, &p); err != nil {
+		return nil, err
+	}
+
+	vdisk, found := s.VDisks[p.VirtualDisk]
+	if !found {
+		return notFound(p.VirtualDisk), nil
+	}
+	return ok(append([]string{}, vdisk.Exports...)), nil
+}
+
+func listTargets(s *Server, params json.RawMessage) (interface{}, error) {
+	return ok(s.Targets), nil
+}
+
+func persistACLAccess(s *Server, params json.RawMessage) (interface{}, error) {
+	var p client.PersistACLAccess
+	if err := json.Unmarshal(params, &p); err != nil {
+		return nil, err
+	}
+
+	results := []interface{}{}
+	for _, name := range p.VirtualDisks {
+		vdisk, found := s.VDisks[name]
+		if !found {
+			results = append(results, diskResult(name, "error", fmt.Sprintf("Virtual disk %s couldn't be found", name)))
+			continue
+		}
+		if !contains(vdisk.ACL[p.Host], p.Address) {
+			vdisk.ACL[p.Host] = append(vdisk.ACL[p.Host], p.Address)
+		}
+		results = append(results, diskResult(name, "ok", ""))
+	}
+	return ok(results), nil
+}
+
+func getACLInformation(s *Server, params json.RawMessage) (interface{}, error) {
+	var p client.GetACLInformation
+	if err := json.Unmarshal(params, &p); err != nil {
+		return nil, err
+	}
+
+	vdisk, found := s.VDisks[p.VirtualDisk]
+	if !found {
+		return notFound(p.VirtualDisk), nil
+	}
+
+	acls := []interface{}{}
+	for _, host := range sortedKeys(vdisk.ACL) {
+		initiators := []interface{}{}
+		for _, address := range vdisk.ACL[host] {
+			initiators = append(initiators, map[string]interface{}{"ip": address, "name": address})
+		}
+		acls = append(acls, map[string]interface{}{"host": host, "initiator": initiators})
+	}
+	return ok(acls), nil
+}
+
+func removeACLAccess(s *Server, params json.RawMessage) (interface{}, error) {
+	var p client.RemoveACLAccess
+	if err := json.Unmarshal(params, &p); err != nil {
+		return nil, err
+	}
+
+	vdisk, found := s.VDisks[p.VirtualDisk]
+	if !found {
+		return notFound(p.VirtualDisk), nil
+	}
+	for _, address := range p.Address {
+		vdisk.ACL[p.Host] = remove(vdisk.ACL[p.Host], address)
+	}
+	if len(vdisk.ACL[p.Host]) == 0 {
+		delete(vdisk.ACL, p.Host)
+	}
+	return ok(nil), nil
+}
@@ -0,0 +1,259 @@
+// Package clienttest provides an in-memory Hedvig REST server for tests.
+package clienttest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/terraform-providers/terraform-provider-hedvig/hedvig/client"
+)
+
+// VDisk is a virtual disk held by the fake server.
+type VDisk struct {
+	client.AddVirtualDisk
+
+	// Luns are the controllers the disk is exported through as a LUN.
+	Luns []string
+	// Exports are the controllers the disk is mounted on over NFS.
+	Exports []string
+	// ACL maps hosts to the addresses allowed to access the disk.
+	ACL map[string][]string
+
+	Snapshots []client.Snapshot
+	// RolledBackTo is the snapshot the disk was last rolled back to.
+	RolledBackTo string
+
+	// ResizeLag is the number of VirtualDiskDetails calls that still report
+	// the previous size after a resize.
+	ResizeLag int
+
+	// MigrationLag is the number of VirtualDiskDetails calls that report a
+	// migration in progress after one is started.
+	MigrationLag int
+
+	reportedSize *client.Size
+	lag          int
+	migrating    int
+}
+
+// Server is an httptest.Server speaking enough of the Hedvig REST API to
+// exercise the provider. All exported fields may be changed between
+// requests while holding Lock.
+type Server struct {
+	*httptest.Server
+	sync.Mutex
+
+	Username string
+	Password string
+
+	// Targets are the storage controllers returned by ListTargets.
+	Targets []client.Target
+	// KMSConfigured must be set for encrypted disks to be created.
+	KMSConfigured bool
+	// Capacity is the raw capacity in bytes of each residence tier.
+	Capacity map[string]int64
+
+	VDisks           map[string]*VDisk
+	SnapshotPolicies map[string]*client.SnapshotPolicy
+
+	// Requests counts the calls made per API type.
+	Requests map[string]int
+	// Unavailable is the number of upcoming calls per API type that fail
+	// with 502 Bad Gateway before reaching the cluster.
+	Unavailable map[string]int
+	// ExpireSessions is the number of upcoming calls per API type before
+	// which every session handed out so far expires.
+	ExpireSessions map[string]int
+
+	sessions   map[string]bool
+	sessionNo  int
+	snapshotNo int
+}
+
+type envelope struct {
+	Type      string          `json:"type"`
+	Category  string          `json:"category"`
+	Params    json.RawMessage `json:"params"`
+	SessionID string          `json:"sessionId"`
+}
+
+// NewServer starts a fake cluster accepting the given credentials.
+func NewServer(username, password string) *Server {
+	s := &Server{
+		Username: username,
+		Password: password,
+		Targets: []client.Target{
+			{Protocol: "iscsi", Target: "iscsi.example.com"},
+			{Protocol: "nfs", Target: "nfs.example.com"},
+		},
+		Capacity:         map[string]int64{"HDD": 100 << 40, "FLASH": 100 << 40},
+		VDisks:           map[string]*VDisk{},
+		SnapshotPolicies: map[string]*client.SnapshotPolicy{},
+		Requests:         map[string]int{},
+		Unavailable:      map[string]int{},
+		ExpireSessions:   map[string]int{},
+		sessions:         map[string]bool{},
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	return s
+}
+
+// Node returns the host:port to configure as the provider's node.
+func (s *Server) Node() string {
+	u, _ := url.Parse(s.URL)
+	return u.Host
+}
+
+// VDisk returns a copy of a stored disk, or nil.
+func (s *Server) VDisk(name string) *VDisk {
+	s.Lock()
+	defer s.Unlock()
+
+	vdisk, ok := s.VDisks[name]
+	if !ok {
+		return nil
+	}
+	copied := *vdisk
+	return &copied
+}
+
+type handler func(s *Server, params json.RawMessage) (interface{}, error)
+
+var handlers = map[string]handler{}
+
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	raw := r.URL.Query().Get("request")
+	if raw == "" {
+		// Health checks hit /rest/ without a request.
+		return
+	}
+
+	var env envelope
+	if err := json.Unmarshal([]byte(raw), &env); err != nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	s.Lock()
+	defer s.Unlock()
+
+	s.Requests[env.Type]++
+
+	if s.Unavailable[env.Type] > 0 {
+		s.Unavailable[env.Type]--
+		http.Error(w, "Bad Gateway", http.StatusBadGateway)
+		return
+	}
+	if s.ExpireSessions[env.Type] > 0 {
+		s.ExpireSessions[env.Type]--
+		s.sessions = map[string]bool{}
+	}
+
+	var resp interface{}
+	switch {
+	case env.Type == "Login":
+		resp = s.login(env.Params)
+	case !s.sessions[env.SessionID]:
+		resp = failure("error", "Invalid session id")
+	case env.Type == "Logout":
+		delete(s.sessions, env.SessionID)
+		resp = ok(nil)
+	default:
+		h, found := handlers[env.Type]
+		if !found {
+			http.NotFound(w, r)
+			return
+		}
+
+		var err error
+		resp, err = h(s, env.Params)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+func (s *Server) login(params json.RawMessage) interface{} {
+	var p struct {
+		UserName string `json:"userName"`
+		Password string `json:"password"`
+	}
+	json.Unmarshal(params, &p)
+
+	if p.UserName != s.Username || p.Password != s.Password {
+		return failure("error", "Login failed: invalid username or password")
+	}
+
+	s.sessionNo++
+	id := fmt.Sprintf("session-%d", s.sessionNo)
+	s.sessions[id] = true
+
+	return ok(map[string]interface{}{"sessionId": id, "userName": p.UserName})
+}
+
+func ok(result interface{}) map[string]interface{} {
+	resp := map[string]interface{}{"status": "ok", "requestId": "fake"}
+	if result != nil {
+		resp["result"] = result
+	}
+	return resp
+}
+
+func failure(status, message string) map[string]interface{} {
+	return map[string]interface{}{"status": status, "message": message, "requestId": "fake"}
+}
+
+func notFound(vdisk string) map[string]interface{} {
+	return failure("warning", fmt.Sprintf("Virtual disk %s couldn't be found", vdisk))
+}
+
+func diskResult(name, status, message string) map[string]interface{} {
+	return map[string]interface{}{"name": name, "status": status, "message": message}
+}
+
+func remove(list []string, s string) []string {
+	kept := list[:0]
+	for _, v := range list {
+		if v != s {
+			kept = append(kept, v)
+		}
+	}
+	return kept
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+func sortedKeys(m map[string][]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func (s *Server) hasTarget(protocol, target string) bool {
+	for _, t := range s.Targets {
+		if strings.EqualFold(t.Protocol, protocol) && t.Target == target {
+			return true
+		}
+	}
+	return false
+}
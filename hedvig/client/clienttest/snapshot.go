@@ -0,0 +1,195 @@
+package clienttest
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/terraform-providers/terraform-provider-hedvig/hedvig/client"
+)
+
+func init() {
+	handlers["CreateSnapshot"] = createSnapshot
+	handlers["ListSnapshots"] = listSnapshots
+	handlers["DeleteSnapshot"] = deleteSnapshot
+	handlers["RollbackVirtualDisk"] = rollbackVirtualDisk
+}
+
+func createSnapshot(s *Server, params json.RawMessage) (interface{}, error) {
+	var p client.CreateSnapshot
+	if err := json.Unmarshal(params, &p); err != nil {
+		return nil, err
+	}
+
+	vdisk, found := s.VDisks[p.VirtualDisk]
+	if !found {
+		return notFound(p.VirtualDisk), nil
+	}
+
+	s.snapshotNo++
+	snapshot := client.Snapshot{
+		Name:         fmt.Sprintf("%s-snap-%d", p.VirtualDisk, s.snapshotNo),
+		VirtualDisk:  p.VirtualDisk,
+		CreationTime: time.Now().UnixNano() / int64(time.Millisecond),
+		Size:         client.ReportedSize{Units: vdisk.Size.Unit, Value: vdisk.Size.Value},
+	}
+	vdisk.Snapshots = append(vdisk.Snapshots, snapshot)
+	return ok(snapshot), nil
+}
+
+func listSnapshots(s *Server, params json.RawMessage) (interface{}, error) {
+	var p client.ListSnapshots
+	if err := json.Unmarshal(params, &p); err != nil {
+		return nil, err
+	}
+
+	vdisk, found := s.VDisks[p.VirtualDisk]
+	if !found {
+		return notFound(p.VirtualDisk), nil
+	}
+
+	snapshots := vdisk.Snapshots
+	if snapshots == nil {
+		snapshots = []client.Snapshot{}
+	}
+	return ok(snapshots), nil
+}
+
+func deleteSnapshot(s *Server, params json.RawMessage) (interface{}, error) {
+	var p client.DeleteSnapshot
+	if err := json.Unmarshal(params, &p); err != nil {
+		return nil, err
+	}
+
+	vdisk, found := s.VDisks[p.VirtualDisk]
+	if !found {
+		return notFound(p.VirtualDisk), nil
+	}
+
+	results := []interface{}{}
+	for _, name := range p.Snapshots {
+		i := snapshotIndex(vdisk, name)
+		if i < 0 {
+			results = append(results, diskResult(name, "warning", fmt.Sprintf("Snapshot %s couldn't be found", name)))
+			continue
+		}
+		vdisk.Snapshots = append(vdisk.Snapshots[:i], vdisk.Snapshots[i+1:]...)
+		results = append(results, diskResult(name, "ok", ""))
+	}
+	return ok(results), nil
+}
+
+func rollbackVirtualDisk(s *Server, params json.RawMessage) (interface{}, error) {
+	var p client.RollbackVirtualDisk
+	if err := json.Unmarshal(params, &p); err != nil {
+		return nil, err
+	}
+
+	vdisk, found := s.VDisks[p.VirtualDisk]
+	if !found {
+		return notFound(p.VirtualDisk), nil
+	}
+
+	i := snapshotIndex(vdisk, p.Snapshot)
+	switch {
+	case i < 0:
+		return ok([]interface{}{diskResult(p.VirtualDisk, "warning", fmt.Sprintf("Snapshot %s couldn't be found", p.Snapshot))}), nil
+	case len(vdisk.Luns) > 0 || len(vdisk.Exports) > 0:
+		return ok([]interface{}{diskResult(p.VirtualDisk, "error", fmt.Sprintf("Virtual disk %s is exported", p.VirtualDisk))}), nil
+	}
+
+	size := vdisk.Snapshots[i].Size
+	vdisk.Size = client.Size{Unit: size.Units, Value: size.Value}
+	vdisk.RolledBackTo = p.Snapshot
+	return ok([]interface{}{diskResult(p.VirtualDisk, "ok", "")}), nil
+}
+
+func snapshotIndex(vdisk *VDisk, name string) int {
+	for i, snapshot := range vdisk.Snapshots {
+		if snapshot.Name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+func init() {
+	handlers["AddSnapshotPolicy"] = addSnapshotPolicy
+	handlers["SnapshotPolicyDetails"] = snapshotPolicyDetails
+	handlers["UpdateSnapshotPolicy"] = updateSnapshotPolicy
+	handlers["DeleteSnapshotPolicy"] = deleteSnapshotPolicy
+}
+
+func addSnapshotPolicy(s *Server, params json.RawMessage) (interface{}, error) {
+	var p client.AddSnapshotPolicy
+	if err := json.Unmarshal(params, &p); err != nil {
+		return nil, err
+	}
+
+	if s.SnapshotPolicies[p.Name] != nil {
+		return failure("error", fmt.Sprintf("Snapshot policy %s already exists", p.Name)), nil
+	}
+	if resp := s.checkSnapshotPolicy(&p.SnapshotPolicy); resp != nil {
+		return resp, nil
+	}
+
+	policy := p.SnapshotPolicy
+	s.SnapshotPolicies[p.Name] = &policy
+	return ok(nil), nil
+}
+
+func snapshotPolicyDetails(s *Server, params json.RawMessage) (interface{}, error) {
+	var p client.SnapshotPolicyDetails
+	if err := json.Unmarshal(params, &p); err != nil {
+		return nil, err
+	}
+
+	policy, found := s.SnapshotPolicies[p.Name]
+	if !found {
+		return failure("warning", fmt.Sprintf("Snapshot policy %s couldn't be found", p.Name)), nil
+	}
+	return ok(policy), nil
+}
+
+func updateSnapshotPolicy(s *Server, params json.RawMessage) (interface{}, error) {
+	var p client.UpdateSnapshotPolicy
+	if err := json.Unmarshal(params, &p); err != nil {
+		return nil, err
+	}
+
+	if s.SnapshotPolicies[p.Name] == nil {
+		return failure("warning", fmt.Sprintf("Snapshot policy %s couldn't be found", p.Name)), nil
+	}
+	if resp := s.checkSnapshotPolicy(&p.SnapshotPolicy); resp != nil {
+		return resp, nil
+	}
+
+	policy := p.SnapshotPolicy
+	s.SnapshotPolicies[p.Name] = &policy
+	return ok(nil), nil
+}
+
+func deleteSnapshotPolicy(s *Server, params json.RawMessage) (interface{}, error) {
+	var p client.DeleteSnapshotPolicy
+	if err := json.Unmarshal(params, &p); err != nil {
+		return nil, err
+	}
+
+	if s.SnapshotPolicies[p.Name] == nil {
+		return failure("warning", fmt.Sprintf("Snapshot policy %s couldn't be found", p.Name)), nil
+	}
+	delete(s.SnapshotPolicies, p.Name)
+	return ok(nil), nil
+}
+
+func (s *Server) checkSnapshotPolicy(p *client.SnapshotPolicy) map[string]interface{} {
+	if (p.IntervalMinutes == 0) == (p.Cron == "") {
+		return failure("error", "Exactly one of interval or cron schedule must be given")
+	}
+	for _, vdisk := range p.VirtualDisks {
+		if s.VDisks[vdisk] == nil {
+			return notFound(vdisk)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,218 @@
+package clienttest
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/terraform-providers/terraform-provider-hedvig/hedvig/client"
+)
+
+func init() {
+	handlers["AddVirtualDisk"] = addVirtualDisk
+	handlers["CloneVirtualDisk"] = cloneVirtualDisk
+	handlers["VirtualDiskDetails"] = virtualDiskDetails
+	handlers["ResizeDisks"] = resizeDisks
+	handlers["UpdateVirtualDisk"] = updateVirtualDisk
+	handlers["MigrateVirtualDisk"] = migrateVirtualDisk
+	handlers["DeleteVDisk"] = deleteVDisk
+}
+
+func addVirtualDisk(s *Server, params json.RawMessage) (interface{}, error) {
+	var p client.AddVirtualDisk
+	if err := json.Unmarshal(params, &p); err != nil {
+		return nil, err
+	}
+
+	switch {
+	case s.VDisks[p.Name] != nil:
+		return ok([]interface{}{diskResult(p.Name, "error", fmt.Sprintf("Virtual disk %s already exists", p.Name))}), nil
+	case p.Encryption && !s.KMSConfigured:
+		return failure("error", "KMS info not found. Run setkmsinfo command"), nil
+	}
+
+	s.VDisks[p.Name] = &VDisk{AddVirtualDisk: p, ACL: map[string][]string{}}
+	return ok([]interface{}{diskResult(p.Name, "ok", "")}), nil
+}
+
+func cloneVirtualDisk(s *Server, params json.RawMessage) (interface{}, error) {
+	var p client.CloneVirtualDisk
+	if err := json.Unmarshal(params, &p); err != nil {
+		return nil, err
+	}
+
+	source, found := s.VDisks[p.Source]
+	if !found {
+		return notFound(p.Source), nil
+	}
+	if s.VDisks[p.Name] != nil {
+		return ok([]interface{}{diskResult(p.Name, "error", fmt.Sprintf("Virtual disk %s already exists", p.Name))}), nil
+	}
+
+	clone := &VDisk{AddVirtualDisk: source.AddVirtualDisk, ACL: map[string][]string{}}
+	clone.Name = p.Name
+	clone.Description = p.Description
+	if p.Snapshot != "" {
+		i := snapshotIndex(source, p.Snapshot)
+		if i < 0 {
+			return failure("warning", fmt.Sprintf("Snapshot %s couldn't be found", p.Snapshot)), nil
+		}
+		size := source.Snapshots[i].Size
+		clone.Size = client.Size{Unit: size.Units, Value: size.Value}
+	}
+
+	s.VDisks[p.Name] = clone
+	return ok([]interface{}{diskResult(p.Name, "ok", "")}), nil
+}
+
+func virtualDiskDetails(s *Server, params json.RawMessage) (interface{}, error) {
+	var p client.VirtualDiskDetails
+	if err := json.Unmarshal(params, &p); err != nil {
+		return nil, err
+	}
+
+	vdisk, found := s.VDisks[p.VirtualDisk]
+	if !found {
+		return notFound(p.VirtualDisk), nil
+	}
+
+	return ok(vdisk.details()), nil
+}
+
+// details renders a disk the way VirtualDiskDetails reports it.
+func (v *VDisk) details() map[string]interface{} {
+	diskType := strings.ToUpper(v.DiskType)
+	if diskType == "NFS" {
+		diskType = "NFS_MASTER_DISK"
+	}
+
+	locations := []string{}
+	for _, lun := range v.Luns {
+		locations = append(locations, lun+":3260")
+	}
+
+	size := v.Size
+	if v.lag > 0 {
+		size = *v.reportedSize
+		v.lag--
+	}
+
+	migrating := v.migrating > 0
+	if migrating {
+		v.migrating--
+	}
+
+	// Like the cluster, report residence in upper case and the default
+	// replication policy by its older name.
+	policy := v.ReplicationPolicy
+	if strings.EqualFold(policy, "Agnostic") {
+		policy = "RackUnaware"
+	}
+
+	return map[string]interface{}{
+		"vDiskName":           v.Name,
+		"size":                map[string]interface{}{"units": size.Unit, "value": size.Value},
+		"diskType":            diskType,
+		"residence":           strings.ToUpper(v.Residence),
+		"replicationFactor":   v.ReplicationFactor,
+		"replicationPolicy":   policy,
+		"deduplication":       v.Deduplication,
+		"compressed":          v.Compressed,
+		"blockSize":           v.BlockSize,
+		"clusteredFileSystem": v.ClusteredFileSystem,
+		"scsi3pr":             v.Scsi3pr,
+		"cacheEnabled":        v.CacheEnabled,
+		"encryption":          v.Encryption,
+		"description":         v.Description,
+		"targetLocations":     locations,
+		"migrationInProgress": migrating,
+	}
+}
+
+func resizeDisks(s *Server, params json.RawMessage) (interface{}, error) {
+	var p client.ResizeDisks
+	if err := json.Unmarshal(params, &p); err != nil {
+		return nil, err
+	}
+
+	results := []interface{}{}
+	for _, name := range p.VirtualDisks {
+		vdisk, found := s.VDisks[name]
+		switch {
+		case !found:
+			results = append(results, diskResult(name, "error", "Virtual disk couldn't be found"))
+		case p.Size.Bytes() < vdisk.Size.Bytes():
+			results = append(results, diskResult(name, "error", "Cannot shrink a virtual disk"))
+		default:
+			if vdisk.ResizeLag > 0 {
+				previous := vdisk.Size
+				vdisk.reportedSize, vdisk.lag = &previous, vdisk.ResizeLag
+			}
+			vdisk.Size = p.Size
+			results = append(results, diskResult(name, "ok", ""))
+		}
+	}
+	return ok(results), nil
+}
+
+func updateVirtualDisk(s *Server, params json.RawMessage) (interface{}, error) {
+	var p client.UpdateVirtualDisk
+	if err := json.Unmarshal(params, &p); err != nil {
+		return nil, err
+	}
+
+	vdisk, found := s.VDisks[p.VirtualDisk]
+	if !found {
+		return notFound(p.VirtualDisk), nil
+	}
+
+	if p.Description != nil {
+		vdisk.Description = *p.Description
+	}
+	if p.CacheEnabled != nil {
+		vdisk.CacheEnabled = *p.CacheEnabled
+	}
+	return ok([]interface{}{diskResult(p.VirtualDisk, "ok", "")}), nil
+}
+
+func migrateVirtualDisk(s *Server, params json.RawMessage) (interface{}, error) {
+	var p client.MigrateVirtualDisk
+	if err := json.Unmarshal(params, &p); err != nil {
+		return nil, err
+	}
+
+	vdisk, found := s.VDisks[p.VirtualDisk]
+	if !found {
+		return notFound(p.VirtualDisk), nil
+	}
+
+	if p.Residence != "" {
+		vdisk.Residence = p.Residence
+	}
+	if p.ReplicationFactor != 0 {
+		vdisk.ReplicationFactor = p.ReplicationFactor
+	}
+	if p.ReplicationPolicy != "" {
+		vdisk.ReplicationPolicy = p.ReplicationPolicy
+	}
+	vdisk.migrating = vdisk.MigrationLag
+	return ok([]interface{}{diskResult(p.VirtualDisk, "ok", "")}), nil
+}
+
+func deleteVDisk(s *Server, params json.RawMessage) (interface{}, error) {
+	var p client.DeleteVDisk
+	if err := json.Unmarshal(params, &p); err != nil {
+		return nil, err
+	}
+
+	results := []interface{}{}
+	for _, name := range p.VirtualDisks {
+		if _, found := s.VDisks[name]; !found {
+			results = append(results, diskResult(name, "warning", fmt.Sprintf("Virtual disk %s couldn't be found", name)))
+			continue
+		}
+		delete(s.VDisks, name)
+		results = append(results, diskResult(name, "ok", ""))
+	}
+	return ok(results), nil
+}
@@ -0,0 +1,51 @@
+package client
+
+import (
+	"context"
+	"strings"
+)
+
+const categoryCluster = "ClusterInformation"
+
+type ClusterCapacity struct{}
+
+func (ClusterCapacity) Type() string     { return "ClusterCapacity" }
+func (ClusterCapacity) Category() string { return categoryCluster }
+
+// TierCapacity is the raw capacity of the disks of one residence. Replicas
+// count towards Used, so a vdisk takes its size times its replication factor.
+type TierCapacity struct {
+	Residence string       `json:"residence"`
+	Total     ReportedSize `json:"totalCapacity"`
+	Used      ReportedSize `json:"usedCapacity"`
+}
+
+// FreeBytes is the capacity of the tier that is not in use.
+func (t *TierCapacity) FreeBytes() int64 {
+	return t.Total.Bytes() - t.Used.Bytes()
+}
+
+type ClusterCapacityResponse struct {
+	Response
+	Result []TierCapacity `json:"result"`
+}
+
+// Tier returns the capacity of a residence, or nil if the cluster has no
+// disks of it.
+func (r *ClusterCapacityResponse) Tier(residence string) *TierCapacity {
+	for i := range r.Result {
+		if strings.EqualFold(r.Result[i].Residence, residence) {
+			return &r.Result[i]
+		}
+	}
+	return nil
+}
+
+// ClusterCapacity returns the capacity of each residence tier of the cluster.
+func (c *Client) ClusterCapacity(ctx context.Context) (*ClusterCapacityResponse, error) {
+	resp := &ClusterCapacityResponse{}
+	if err := c.read(ctx, &ClusterCapacity{}, resp); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
@@ -0,0 +1,135 @@
+package client
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// HedvigAPIError is returned when a node answers with an HTTP error or the
+// API reports a status other than ok, either for the whole request or for
+// one of the items it acted on.
+type HedvigAPIError struct {
+	// HTTPStatus is the HTTP status code of the response.
+	HTTPStatus int
+	// Status is the API status, usually warning or error. It is empty when
+	// the request failed at the HTTP level.
+	Status      string
+	Message     string
+	RequestID   string
+	RequestType string
+}
+
+func (e *HedvigAPIError) Error() string {
+	if e.Status == "" {
+		return fmt.Sprintf("%s failed: HTTP %d %s", e.RequestType, e.HTTPStatus, http.StatusText(e.HTTPStatus))
+	}
+	if e.Message == "" {
+		return fmt.Sprintf("%s failed with status %s", e.RequestType, e.Status)
+	}
+	return fmt.Sprintf("%s failed: %s", e.RequestType, e.Message)
+}
+
+// itemError reports the failure of a single item of a bulk request.
+func itemError(resp *Response, req Request, status, message string) *HedvigAPIError {
+	return &HedvigAPIError{
+		HTTPStatus:  http.StatusOK,
+		Status:      status,
+		Message:     message,
+		RequestID:   resp.RequestID,
+		RequestType: req.Type(),
+	}
+}
+
+func asAPIError(err error) (*HedvigAPIError, bool) {
+	e, ok := err.(*HedvigAPIError)
+	return e, ok
+}
+
+func (e *HedvigAPIError) messageContains(substrs ...string) bool {
+	msg := strings.ToLower(e.Message)
+	for _, s := range substrs {
+		if strings.Contains(msg, strings.ToLower(s)) {
+			return true
+		}
+	}
+	return false
+}
+
+// busyStatuses and busyMessages tell that the cluster is temporarily unable
+// to serve a request. They are also the defaults of RetryPolicy.
+var (
+	busyStatuses = []int{
+		http.StatusTooManyRequests,
+		http.StatusBadGateway,
+		http.StatusServiceUnavailable,
+		http.StatusGatewayTimeout,
+	}
+	busyMessages = []string{
+		"busy",
+		"try again",
+	}
+)
+
+// transient reports whether e has one of statuses as its HTTP status, or is
+// a failed API response whose message contains one of messages.
+func (e *HedvigAPIError) transient(statuses []int, messages []string) bool {
+	for _, code := range statuses {
+		if e.HTTPStatus == code {
+			return true
+		}
+	}
+	return e.Status != "" && e.messageContains(messages...)
+}
+
+// IsNotFound reports whether err says the object acted on does not exist.
+func IsNotFound(err error) bool {
+	e, ok := asAPIError(err)
+	if !ok || e.Status == "" {
+		return false
+	}
+	return strings.HasSuffix(e.Message, "t be found") ||
+		e.messageContains("not found", "does not exist")
+}
+
+// IsAlreadyExists reports whether err says the object to create exists.
+func IsAlreadyExists(err error) bool {
+	e, ok := asAPIError(err)
+	return ok && e.messageContains("already exists", "already in use")
+}
+
+// IsAuth reports whether err is caused by missing, invalid or expired
+// credentials.
+func IsAuth(err error) bool {
+	e, ok := asAPIError(err)
+	if !ok {
+		return false
+	}
+	if e.HTTPStatus == http.StatusUnauthorized || e.HTTPStatus == http.StatusForbidden {
+		return true
+	}
+	msg := strings.ToLower(e.Message)
+	return (strings.Contains(msg, "session") && (strings.Contains(msg, "expired") || strings.Contains(msg, "invalid"))) ||
+		e.messageContains("authentication", "login failed", "invalid credentials", "invalid username or password")
+}
+
+// IsKMSNotConfigured reports whether err says encryption needs a key
+// management server to be set up first.
+func IsKMSNotConfigured(err error) bool {
+	e, ok := asAPIError(err)
+	return ok && e.messageContains("Run setkmsinfo command")
+}
+
+// IsNotNFSController reports whether err says the controller a disk was to
+// be mounted on does not serve NFS.
+func IsNotNFSController(err error) bool {
+	e, ok := asAPIError(err)
+	return ok && e.messageContains("trying to get handle to")
+}
+
+// IsBusy reports whether err says the cluster is temporarily unable to
+// serve the request.
+func IsBusy(err error) bool {
+	e, ok := asAPIError(err)
+	return ok && e.transient(busyStatuses, busyMessages)
+}
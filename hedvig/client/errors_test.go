@@ -0,0 +1,75 @@
+package client
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+)
+
+func TestHedvigAPIErrorClassification(t *testing.T) {
+	apiErr := func(httpStatus int, status, message string) error {
+		return &HedvigAPIError{HTTPStatus: httpStatus, Status: status, Message: message, RequestType: "Test"}
+	}
+
+	cases := []struct {
+		name                                      string
+		err                                       error
+		notFound, exists, auth, kms, busy, notNFS bool
+	}{
+		{name: "nil", err: nil},
+		{name: "plain error", err: errors.New("could not be found")},
+		{name: "vdisk missing", err: apiErr(200, "warning", "Virtual disk foo couldn't be found"), notFound: true},
+		{name: "does not exist", err: apiErr(200, "error", "Target does not exist"), notFound: true},
+		{name: "already exists", err: apiErr(200, "error", "Virtual disk foo already exists"), exists: true},
+		{name: "expired session", err: apiErr(200, "error", "Session expired"), auth: true},
+		{name: "unauthorized", err: apiErr(http.StatusUnauthorized, "", ""), auth: true},
+		{name: "kms", err: apiErr(200, "error", "KMS is not configured. Run setkmsinfo command"), kms: true},
+		{name: "busy", err: apiErr(200, "error", "Cluster is busy, please try again"), busy: true},
+		{name: "unavailable", err: apiErr(http.StatusServiceUnavailable, "", ""), busy: true},
+		{name: "busy without status", err: apiErr(http.StatusOK, "", "try again")},
+		{name: "not nfs", err: apiErr(200, "error", "Error trying to get handle to nfs export on iscsi.example.com"), notNFS: true},
+		{name: "malformed", err: apiErr(http.StatusNotFound, "", "Malformed query; aborting")},
+	}
+
+	for _, tc := range cases {
+		if got := IsNotFound(tc.err); got != tc.notFound {
+			t.Errorf("%s: IsNotFound = %t", tc.name, got)
+		}
+		if got := IsAlreadyExists(tc.err); got != tc.exists {
+			t.Errorf("%s: IsAlreadyExists = %t", tc.name, got)
+		}
+		if got := IsAuth(tc.err); got != tc.auth {
+			t.Errorf("%s: IsAuth = %t", tc.name, got)
+		}
+		if got := IsKMSNotConfigured(tc.err); got != tc.kms {
+			t.Errorf("%s: IsKMSNotConfigured = %t", tc.name, got)
+		}
+		if got := IsBusy(tc.err); got != tc.busy {
+			t.Errorf("%s: IsBusy = %t", tc.name, got)
+		}
+		if got := IsNotNFSController(tc.err); got != tc.notNFS {
+			t.Errorf("%s: IsNotNFSController = %t", tc.name, got)
+		}
+	}
+}
+
+func TestCheckDiskResults(t *testing.T) {
+	resp := &Response{RequestID: "r1", Message: "nothing happened"}
+	req := &DeleteVDisk{}
+
+	err := checkDiskResults(resp, req, []DiskResult{{Name: "a", Status: "ok"}, {Name: "b", Status: "error", Message: "busy"}})
+	apiErr, ok := err.(*HedvigAPIError)
+	if !ok {
+		t.Fatalf("expected a HedvigAPIError, got %#v", err)
+	}
+	if apiErr.RequestID != "r1" || apiErr.RequestType != "DeleteVDisk" || apiErr.Message != "busy" {
+		t.Fatalf("unexpected error fields: %#v", apiErr)
+	}
+
+	if err := checkDiskResults(resp, req, nil); err == nil {
+		t.Fatal("expected an error for an empty result")
+	}
+	if err := checkDiskResults(resp, req, []DiskResult{{Name: "a", Status: "ok"}}); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,71 @@
+package client
+
+import (
+	"context"
+	"strings"
+)
+
+// Exports lists how a vdisk is made available to hosts.
+type Exports struct {
+	// Luns are the controllers exporting the disk as an iSCSI LUN.
+	Luns []string
+	// Mounts are the controllers exporting the disk over NFS.
+	Mounts []string
+}
+
+// Exported reports whether the disk is exported at all.
+func (e *Exports) Exported() bool {
+	return len(e.Luns) > 0 || len(e.Mounts) > 0
+}
+
+// Exports returns the LUNs and NFS exports of a vdisk.
+func (c *Client) Exports(ctx context.Context, vdisk string) (*Exports, error) {
+	details, err := c.VirtualDiskDetails(ctx, vdisk)
+	if err != nil {
+		return nil, err
+	}
+
+	exports := &Exports{}
+	for _, location := range details.Result.TargetLocations {
+		// Locations are reported as controller:port.
+		exports.Luns = append(exports.Luns, strings.SplitN(location, ":", 2)[0])
+	}
+
+	mounts, err := c.ListExportedTargets(ctx, vdisk)
+	if err != nil {
+		return nil, err
+	}
+	exports.Mounts = mounts.Result
+
+	return exports, nil
+}
+
+// Unexport removes all of the given exports of a vdisk.
+func (c *Client) Unexport(ctx context.Context, vdisk string, exports *Exports) error {
+	for _, target := range exports.Luns {
+		if _, err := c.UnmapLun(ctx, &UnmapLun{VirtualDisk: vdisk, Target: target}); err != nil {
+			return err
+		}
+	}
+	if len(exports.Mounts) > 0 {
+		if _, err := c.Unmount(ctx, &Unmount{VirtualDisk: vdisk, Targets: exports.Mounts}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Reexport restores exports previously removed with Unexport.
+func (c *Client) Reexport(ctx context.Context, vdisk string, exports *Exports) error {
+	if len(exports.Luns) > 0 {
+		if _, err := c.AddLun(ctx, &AddLun{VirtualDisks: []string{vdisk}, Targets: exports.Luns}); err != nil {
+			return err
+		}
+	}
+	for _, target := range exports.Mounts {
+		if _, err := c.Mount(ctx, &Mount{VirtualDisk: vdisk, Targets: []string{target}}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,77 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+)
+
+// node returns the node requests are currently sent to.
+func (c *Client) node() string {
+	c.nodeMu.Lock()
+	defer c.nodeMu.Unlock()
+
+	return c.Nodes[c.current]
+}
+
+// failover moves away from a node that failed and returns the first of the
+// remaining nodes that passes a health check. The choice is kept for all
+// later requests.
+func (c *Client) failover(ctx context.Context, failed string) (string, error) {
+	c.nodeMu.Lock()
+	defer c.nodeMu.Unlock()
+
+	// Another request may already have moved on to a healthy node.
+	if c.Nodes[c.current] != failed {
+		return c.Nodes[c.current], nil
+	}
+
+	for i := 1; i < len(c.Nodes); i++ {
+		next := (c.current + i) % len(c.Nodes)
+		if c.healthy(ctx, c.Nodes[next]) {
+			log.Printf("[INFO] Failing over from Hedvig node %s to %s", failed, c.Nodes[next])
+			c.current = next
+			return c.Nodes[next], nil
+		}
+	}
+
+	return "", &noHealthyNodeError{Nodes: c.Nodes}
+}
+
+type noHealthyNodeError struct {
+	Nodes []string
+}
+
+func (e *noHealthyNodeError) Error() string {
+	return fmt.Sprintf("No healthy Hedvig node available (tried %s)", strings.Join(e.Nodes, ", "))
+}
+
+// healthy reports whether a node's REST endpoint answers without a server
+// error.
+func (c *Client) healthy(ctx context.Context, node string) bool {
+	resp, err := c.get(ctx, node, nil)
+	if err != nil {
+		log.Printf("[DEBUG] Hedvig node %s failed health check: %s", node, err)
+		return false
+	}
+	resp.Body.Close()
+
+	return resp.StatusCode < http.StatusInternalServerError
+}
+
+// nodeError is returned by do when a node could not be reached or answered
+// with a server error.
+type nodeError struct {
+	Node string
+	Err  error
+}
+
+func (e *nodeError) Error() string {
+	return e.Err.Error()
+}
+
+func nodeFailed(resp *http.Response) bool {
+	return resp.StatusCode >= http.StatusInternalServerError
+}
@@ -0,0 +1,140 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestClientFailover(t *testing.T) {
+	var brokenHits int32
+
+	broken := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&brokenHits, 1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer broken.Close()
+
+	healthy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("request") == "" {
+			return
+		}
+		fmt.Fprint(w, `{"status":"ok","result":{"sessionId":"abc"}}`)
+	}))
+	defer healthy.Close()
+
+	down := httptest.NewServer(http.NotFoundHandler())
+	downURL, _ := url.Parse(down.URL)
+	down.Close()
+
+	brokenURL, _ := url.Parse(broken.URL)
+	healthyURL, _ := url.Parse(healthy.URL)
+
+	c := New([]string{downURL.Host, brokenURL.Host, healthyURL.Host}, "admin", "admin")
+
+	if _, err := c.Login(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if c.node() != healthyURL.Host {
+		t.Fatalf("expected to fail over to %s, using %s", healthyURL.Host, c.node())
+	}
+
+	hits := atomic.LoadInt32(&brokenHits)
+	if _, err := c.Login(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if atomic.LoadInt32(&brokenHits) != hits {
+		t.Fatal("expected the healthy node to be remembered")
+	}
+}
+
+func TestClientFailoverExhausted(t *testing.T) {
+	down := httptest.NewServer(http.NotFoundHandler())
+	downURL, _ := url.Parse(down.URL)
+	down.Close()
+
+	c := New([]string{downURL.Host, downURL.Host}, "admin", "admin")
+	c.Retry.MaxAttempts = 1
+
+	if _, err := c.Login(context.Background()); err == nil {
+		t.Fatal("expected an error when no node is reachable")
+	}
+}
+
+func TestClientFailoverRechecksMutations(t *testing.T) {
+	var mu sync.Mutex
+	var aRequests, bRequests []string
+
+	requestType := func(r *http.Request) string {
+		var env struct {
+			Type string `json:"type"`
+		}
+		json.Unmarshal([]byte(r.URL.Query().Get("request")), &env)
+		return env.Type
+	}
+
+	// Node a drops the connection once it has received AddVirtualDisk, so
+	// the client cannot tell whether the disk was created.
+	a := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		reqType := requestType(r)
+		mu.Lock()
+		aRequests = append(aRequests, reqType)
+		mu.Unlock()
+
+		switch reqType {
+		case "Login":
+			fmt.Fprint(w, `{"status":"ok","result":{"sessionId":"abc"}}`)
+		case "AddVirtualDisk":
+			conn, _, err := w.(http.Hijacker).Hijack()
+			if err != nil {
+				t.Fatal(err)
+			}
+			conn.Close()
+		}
+	}))
+	defer a.Close()
+
+	b := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		reqType := requestType(r)
+		if reqType == "" {
+			return
+		}
+		mu.Lock()
+		bRequests = append(bRequests, reqType)
+		mu.Unlock()
+
+		switch reqType {
+		case "VirtualDiskDetails":
+			fmt.Fprint(w, `{"status":"error","message":"Virtual disk d could not be found"}`)
+		default:
+			fmt.Fprint(w, `{"status":"ok","result":[{"name":"d","status":"ok"}]}`)
+		}
+	}))
+	defer b.Close()
+
+	aURL, _ := url.Parse(a.URL)
+	bURL, _ := url.Parse(b.URL)
+
+	c := New([]string{aURL.Host, bURL.Host}, "admin", "admin")
+	c.Retry.MinBackoff = time.Millisecond
+	c.Retry.MaxBackoff = time.Millisecond
+
+	if _, err := c.AddVirtualDisk(context.Background(), &AddVirtualDisk{Name: "d"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if expected := []string{"Login", "AddVirtualDisk"}; !reflect.DeepEqual(aRequests, expected) {
+		t.Fatalf("expected node a to receive %v, got %v", expected, aRequests)
+	}
+	if expected := []string{"VirtualDiskDetails", "AddVirtualDisk"}; !reflect.DeepEqual(bRequests, expected) {
+		t.Fatalf("expected node b to check for the disk before creating it, got %v", bRequests)
+	}
+}
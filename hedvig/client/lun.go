@@ -0,0 +1,120 @@
+package client
+
+import (
+	"context"
+	"strings"
+)
+
+type AddLun struct {
+	VirtualDisks []string `json:"virtualDisks"`
+	Targets      []string `json:"targets"`
+	Readonly     bool     `json:"readonly"`
+}
+
+func (AddLun) Type() string     { return "AddLun" }
+func (AddLun) Category() string { return categoryVirtualDisk }
+
+// TargetResult is the per-target outcome reported by export operations.
+type TargetResult struct {
+	Name    string `json:"name"`
+	Message string `json:"message"`
+	Status  string `json:"status"`
+}
+
+type LunResult struct {
+	Name    string         `json:"name"`
+	Targets []TargetResult `json:"targets"`
+	Status  string         `json:"status"`
+}
+
+type AddLunResponse struct {
+	Response
+	Result []LunResult `json:"result"`
+}
+
+func (c *Client) AddLun(ctx context.Context, req *AddLun) (*AddLunResponse, error) {
+	resp := &AddLunResponse{}
+	err := c.mutate(ctx, req, resp, func() (bool, error) {
+		applied := AddLunResponse{Response: appliedResponse(req)}
+		for _, name := range req.VirtualDisks {
+			details, err := c.VirtualDiskDetails(ctx, name)
+			if err != nil {
+				return false, err
+			}
+			for _, target := range req.Targets {
+				if !hasTargetLocation(&details.Result, target) {
+					return false, nil
+				}
+			}
+			applied.Result = append(applied.Result, LunResult{Name: name, Status: "ok", Targets: appliedTargets(req.Targets)})
+		}
+		*resp = applied
+		return true, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(resp.Result) < 1 || len(resp.Result[0].Targets) < 1 {
+		return resp, itemError(&resp.Response, req, "error", resp.Message)
+	}
+	for _, result := range resp.Result {
+		for _, target := range result.Targets {
+			if target.Status != "ok" {
+				return resp, itemError(&resp.Response, req, target.Status, target.Message)
+			}
+		}
+	}
+	return resp, nil
+}
+
+type UnmapLun struct {
+	VirtualDisk string `json:"virtualDisk"`
+	Target      string `json:"target"`
+}
+
+func (UnmapLun) Type() string     { return "UnmapLun" }
+func (UnmapLun) Category() string { return categoryVirtualDisk }
+
+type UnmapLunResponse struct {
+	Response
+}
+
+func (c *Client) UnmapLun(ctx context.Context, req *UnmapLun) (*UnmapLunResponse, error) {
+	resp := &UnmapLunResponse{}
+	err := c.mutate(ctx, req, resp, func() (bool, error) {
+		details, err := c.VirtualDiskDetails(ctx, req.VirtualDisk)
+		if err != nil && !IsNotFound(err) {
+			return false, err
+		}
+		if err == nil && hasTargetLocation(&details.Result, req.Target) {
+			return false, nil
+		}
+		*resp = UnmapLunResponse{Response: appliedResponse(req)}
+		return true, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// hasTargetLocation reports whether a vdisk is exported as a LUN through the
+// given controller. Locations are the controller, optionally followed by a
+// colon and a port, so ctrl1 does not match ctrl10:3260.
+func hasTargetLocation(vdisk *VirtualDisk, target string) bool {
+	for _, location := range vdisk.TargetLocations {
+		if location == target || strings.HasPrefix(location, target+":") {
+			return true
+		}
+	}
+	return false
+}
+
+func appliedTargets(targets []string) []TargetResult {
+	results := make([]TargetResult, 0, len(targets))
+	for _, target := range targets {
+		results = append(results, TargetResult{Name: target, Status: "ok"})
+	}
+	return results
+}
@@ -0,0 +1,143 @@
+package client
+
+import "context"
+
+type Mount struct {
+	VirtualDisk string   `json:"virtualDisk"`
+	Targets     []string `json:"targets"`
+}
+
+func (Mount) Type() string     { return "Mount" }
+func (Mount) Category() string { return categoryVirtualDisk }
+
+type ExportInfo struct {
+	Target  string `json:"target"`
+	Message string `json:"message"`
+	Status  string `json:"status"`
+}
+
+type MountResponse struct {
+	Response
+	Result struct {
+		ExportInfo []ExportInfo `json:"exportInfo"`
+	} `json:"result"`
+}
+
+func (c *Client) Mount(ctx context.Context, req *Mount) (*MountResponse, error) {
+	resp := &MountResponse{}
+	err := c.mutate(ctx, req, resp, func() (bool, error) {
+		exported, err := c.ListExportedTargets(ctx, req.VirtualDisk)
+		if err != nil {
+			return false, err
+		}
+		applied := MountResponse{Response: appliedResponse(req)}
+		for _, target := range req.Targets {
+			if !contains(exported.Result, target) {
+				return false, nil
+			}
+			applied.Result.ExportInfo = append(applied.Result.ExportInfo, ExportInfo{Target: target, Status: "ok"})
+		}
+		*resp = applied
+		return true, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(resp.Result.ExportInfo) != 1 {
+		return resp, itemError(&resp.Response, req, "error", "unexpected response from server")
+	}
+	if info := resp.Result.ExportInfo[0]; info.Status != "ok" {
+		return resp, itemError(&resp.Response, req, info.Status, info.Message)
+	}
+	return resp, nil
+}
+
+type Unmount struct {
+	VirtualDisk string   `json:"virtualDisk"`
+	Targets     []string `json:"targets"`
+}
+
+func (Unmount) Type() string     { return "Unmount" }
+func (Unmount) Category() string { return categoryVirtualDisk }
+
+type UnmountResponse struct {
+	Response
+	Result []DiskResult `json:"result"`
+}
+
+func (c *Client) Unmount(ctx context.Context, req *Unmount) (*UnmountResponse, error) {
+	resp := &UnmountResponse{}
+	err := c.mutate(ctx, req, resp, func() (bool, error) {
+		exported, err := c.ListExportedTargets(ctx, req.VirtualDisk)
+		if err != nil && !IsNotFound(err) {
+			return false, err
+		}
+		if err == nil {
+			for _, target := range req.Targets {
+				if contains(exported.Result, target) {
+					return false, nil
+				}
+			}
+		}
+		*resp = UnmountResponse{Response: appliedResponse(req), Result: appliedDisks(req.VirtualDisk)}
+		return true, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+type ListExportedTargets struct {
+	VirtualDisk string `json:"virtualDisk"`
+}
+
+func (ListExportedTargets) Type() string     { return "ListExportedTargets" }
+func (ListExportedTargets) Category() string { return categoryVirtualDisk }
+
+type ListExportedTargetsResponse struct {
+	Response
+	Result []string `json:"result"`
+}
+
+func (c *Client) ListExportedTargets(ctx context.Context, vdisk string) (*ListExportedTargetsResponse, error) {
+	resp := &ListExportedTargetsResponse{}
+	if err := c.read(ctx, &ListExportedTargets{VirtualDisk: vdisk}, resp); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+type ListTargets struct{}
+
+func (ListTargets) Type() string     { return "ListTargets" }
+func (ListTargets) Category() string { return categoryVirtualDisk }
+
+// Target is a storage controller able to export vdisks.
+type Target struct {
+	Protocol string `json:"protocol"`
+	Target   string `json:"target"`
+}
+
+type ListTargetsResponse struct {
+	Response
+	Result []Target `json:"result"`
+}
+
+func (c *Client) ListTargets(ctx context.Context) (*ListTargetsResponse, error) {
+	resp := &ListTargetsResponse{}
+	if err := c.read(ctx, &ListTargets{}, resp); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,132 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math/rand"
+	"net/url"
+	"time"
+)
+
+// RetryPolicy controls how calls failing with a transient error are retried.
+type RetryPolicy struct {
+	// MaxAttempts is the total number of attempts, including the first one.
+	MaxAttempts int
+	MinBackoff  time.Duration
+	MaxBackoff  time.Duration
+	// Jitter randomizes each backoff between half and all of its length.
+	Jitter bool
+
+	// RetryableStatuses are HTTP status codes treated as transient.
+	RetryableStatuses []int
+	// RetryableMessages are matched case-insensitively against the message
+	// of failed API responses.
+	RetryableMessages []string
+}
+
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts: 4,
+		MinBackoff:  time.Second,
+		MaxBackoff:  30 * time.Second,
+		Jitter:      true,
+		// Copied so that changing a policy leaves IsBusy alone.
+		RetryableStatuses: append([]int(nil), busyStatuses...),
+		RetryableMessages: append([]string(nil), busyMessages...),
+	}
+}
+
+func (p *RetryPolicy) retryable(err error) bool {
+	switch e := err.(type) {
+	case *url.Error, *noHealthyNodeError:
+		// Connection level failures are always worth another attempt.
+		return true
+	case *HedvigAPIError:
+		return e.transient(p.RetryableStatuses, p.RetryableMessages)
+	}
+	return false
+}
+
+func (p *RetryPolicy) backoff(attempt int) time.Duration {
+	wait := p.MinBackoff
+	for i := 1; i < attempt && wait < p.MaxBackoff; i++ {
+		wait *= 2
+	}
+	if wait > p.MaxBackoff {
+		wait = p.MaxBackoff
+	}
+
+	if p.Jitter && wait > 1 {
+		wait = wait/2 + time.Duration(rand.Int63n(int64(wait/2)+1))
+	}
+	return wait
+}
+
+// read performs an idempotent call, retrying transient failures.
+func (c *Client) read(ctx context.Context, req Request, out interface{}) error {
+	return c.retry(ctx, req, out, func() error { return c.call(ctx, req, out) }, nil)
+}
+
+// mutate performs a call that changes cluster state. Before a failed call is
+// replayed, applied checks whether it took effect anyway; if so, applied
+// fills in out and no further attempt is made.
+func (c *Client) mutate(ctx context.Context, req Request, out interface{}, applied func() (bool, error)) error {
+	attemptCtx := context.WithValue(ctx, mutationKey{}, true)
+	return c.retry(ctx, req, out, func() error { return c.call(attemptCtx, req, out) }, applied)
+}
+
+// retry runs attempt until it succeeds, fails permanently or the policy's
+// attempts are used up. When the node an attempt was sent to fails, the
+// next healthy node is tried at once, without using up an attempt. Like any
+// other retry, this only replays a mutation once applied has found that it
+// did not take effect.
+func (c *Client) retry(ctx context.Context, req Request, out interface{}, attempt func() error, applied func() (bool, error)) error {
+	p := c.Retry
+	failovers := 0
+	for n := 1; ; {
+		err := attempt()
+
+		failedOver := false
+		if nodeErr, ok := err.(*nodeError); ok {
+			err = nodeErr.Err
+			if failovers < len(c.Nodes)-1 && ctx.Err() == nil {
+				failovers++
+				log.Printf("[WARN] Hedvig node %s failed, trying another node: %s", nodeErr.Node, err)
+				if _, failoverErr := c.failover(ctx, nodeErr.Node); failoverErr != nil {
+					err = failoverErr
+				} else {
+					failedOver = true
+				}
+			}
+		}
+
+		if !failedOver {
+			if n >= p.MaxAttempts || ctx.Err() != nil || !p.retryable(err) {
+				return err
+			}
+
+			wait := p.backoff(n)
+			log.Printf("[WARN] Hedvig %s failed (attempt %d of %d), retrying in %s: %s",
+				req.Type(), n, p.MaxAttempts, wait, err)
+			select {
+			case <-ctx.Done():
+				return fmt.Errorf("Giving up on %s: %s (last error: %s)", req.Type(), ctx.Err(), err)
+			case <-time.After(wait):
+			}
+			n++
+		}
+
+		if applied != nil {
+			ok, checkErr := applied()
+			if checkErr != nil {
+				log.Printf("[WARN] Could not check whether %s was applied: %s", req.Type(), checkErr)
+				return err
+			}
+			if ok {
+				log.Printf("[DEBUG] Hedvig %s was applied despite the error", req.Type())
+				return nil
+			}
+		}
+	}
+}
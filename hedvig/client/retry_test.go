@@ -0,0 +1,257 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+)
+
+// newRetryTestServer starts a node which answers health checks and Login
+// itself and passes every other request to handler.
+func newRetryTestServer(t *testing.T, handler func(w http.ResponseWriter, reqType string)) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		raw := r.URL.Query().Get("request")
+		if raw == "" {
+			return
+		}
+
+		var env struct {
+			Type string `json:"type"`
+		}
+		if err := json.Unmarshal([]byte(raw), &env); err != nil {
+			t.Fatal(err)
+		}
+		if env.Type == "Login" {
+			fmt.Fprint(w, `{"status":"ok","result":{"sessionId":"abc"}}`)
+			return
+		}
+		handler(w, env.Type)
+	}))
+}
+
+func newRetryTestClient(t *testing.T, handlers ...func(w http.ResponseWriter, reqType string)) (*Client, func()) {
+	var servers []*httptest.Server
+	var nodes []string
+	for _, handler := range handlers {
+		ts := newRetryTestServer(t, handler)
+		u, _ := url.Parse(ts.URL)
+		servers = append(servers, ts)
+		nodes = append(nodes, u.Host)
+	}
+
+	c := New(nodes, "admin", "admin")
+	c.Retry.MinBackoff = time.Millisecond
+	c.Retry.MaxBackoff = time.Millisecond
+	return c, func() {
+		for _, ts := range servers {
+			ts.Close()
+		}
+	}
+}
+
+func TestClientRetriesReads(t *testing.T) {
+	calls := 0
+	c, closer := newRetryTestClient(t, func(w http.ResponseWriter, reqType string) {
+		calls++
+		switch calls {
+		case 1:
+			w.WriteHeader(http.StatusServiceUnavailable)
+		case 2:
+			fmt.Fprint(w, `{"status":"error","message":"Cluster is busy"}`)
+		default:
+			fmt.Fprint(w, `{"status":"ok","result":{"vDiskName":"d"}}`)
+		}
+	})
+	defer closer()
+
+	resp, err := c.VirtualDiskDetails(context.Background(), "d")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Status != "ok" || calls != 3 {
+		t.Fatalf("expected success on the third attempt, got %q after %d calls", resp.Status, calls)
+	}
+}
+
+func TestClientDoesNotRetryPermanentErrors(t *testing.T) {
+	calls := 0
+	c, closer := newRetryTestClient(t, func(w http.ResponseWriter, reqType string) {
+		calls++
+		w.WriteHeader(http.StatusBadRequest)
+	})
+	defer closer()
+
+	if _, err := c.VirtualDiskDetails(context.Background(), "d"); err == nil {
+		t.Fatal("expected an error")
+	}
+	if calls != 1 {
+		t.Fatalf("expected a single attempt, got %d", calls)
+	}
+}
+
+func TestClientRechecksMutations(t *testing.T) {
+	adds := 0
+	c, closer := newRetryTestClient(t, func(w http.ResponseWriter, reqType string) {
+		switch reqType {
+		case "AddVirtualDisk":
+			adds++
+			w.WriteHeader(http.StatusBadGateway)
+		case "VirtualDiskDetails":
+			// The first attempt created the disk before the proxy failed.
+			fmt.Fprint(w, `{"status":"ok","result":{"vDiskName":"d"}}`)
+		}
+	})
+	defer closer()
+
+	resp, err := c.AddVirtualDisk(context.Background(), &AddVirtualDisk{Name: "d"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if adds != 1 {
+		t.Fatalf("expected AddVirtualDisk not to be replayed, got %d calls", adds)
+	}
+	if len(resp.Result) != 1 || resp.Result[0].Status != "ok" {
+		t.Fatalf("unexpected response: %#v", resp)
+	}
+}
+
+func TestClientRechecksMutationsOnAnotherNode(t *testing.T) {
+	var first, second []string
+	c, closer := newRetryTestClient(t,
+		func(w http.ResponseWriter, reqType string) {
+			// The node fails after creating the disk.
+			first = append(first, reqType)
+			w.WriteHeader(http.StatusBadGateway)
+		},
+		func(w http.ResponseWriter, reqType string) {
+			second = append(second, reqType)
+			fmt.Fprint(w, `{"status":"ok","result":{"vDiskName":"d"}}`)
+		},
+	)
+	defer closer()
+
+	if _, err := c.AddVirtualDisk(context.Background(), &AddVirtualDisk{Name: "d"}); err != nil {
+		t.Fatal(err)
+	}
+	if len(first) != 1 || first[0] != "AddVirtualDisk" {
+		t.Fatalf("expected a single AddVirtualDisk on the first node, got %v", first)
+	}
+	if len(second) != 1 || second[0] != "VirtualDiskDetails" {
+		t.Fatalf("expected the second node only to be asked for the disk, got %v", second)
+	}
+}
+
+func TestClientRechecksSnapshots(t *testing.T) {
+	now := time.Now().UnixNano() / int64(time.Millisecond)
+	cases := []struct {
+		name string
+		// taken are the snapshots that show up while CreateSnapshot fails.
+		taken   []Snapshot
+		creates int
+		err     bool
+	}{
+		{"applied", []Snapshot{{Name: "new", CreationTime: now + 1000}}, 1, false},
+		{"older", []Snapshot{{Name: "old", CreationTime: now - 60000}}, 2, false},
+		{"ambiguous", []Snapshot{{Name: "new", CreationTime: now + 1000}, {Name: "other", CreationTime: now + 1000}}, 1, true},
+	}
+
+	for _, tc := range cases {
+		var snapshots []Snapshot
+		creates := 0
+		c, closer := newRetryTestClient(t, func(w http.ResponseWriter, reqType string) {
+			switch reqType {
+			case "ListSnapshots":
+				json.NewEncoder(w).Encode(map[string]interface{}{"status": "ok", "result": snapshots})
+			case "CreateSnapshot":
+				creates++
+				if creates == 1 {
+					snapshots = append(snapshots, tc.taken...)
+					w.WriteHeader(http.StatusBadGateway)
+					return
+				}
+				fmt.Fprint(w, `{"status":"ok","result":{"name":"replayed"}}`)
+			}
+		})
+
+		resp, err := c.CreateSnapshot(context.Background(), &CreateSnapshot{VirtualDisk: "d"})
+		closer()
+		if tc.err != (err != nil) {
+			t.Fatalf("%s: unexpected error: %v", tc.name, err)
+		}
+		if creates != tc.creates {
+			t.Fatalf("%s: expected %d CreateSnapshot calls, got %d", tc.name, tc.creates, creates)
+		}
+		if err == nil && tc.creates == 1 && resp.Result.Name != "new" {
+			t.Fatalf("%s: expected the new snapshot, got %q", tc.name, resp.Result.Name)
+		}
+	}
+}
+
+func TestHasTargetLocation(t *testing.T) {
+	cases := []struct {
+		locations []string
+		target    string
+		want      bool
+	}{
+		{[]string{"ctrl1"}, "ctrl1", true},
+		{[]string{"ctrl1:3260"}, "ctrl1", true},
+		{[]string{"ctrl10:3260"}, "ctrl1", false},
+		{[]string{"ctrl10"}, "ctrl1", false},
+		{[]string{"ctrl10:3260", "ctrl1:3260"}, "ctrl1", true},
+		{nil, "ctrl1", false},
+	}
+
+	for _, tc := range cases {
+		vdisk := &VirtualDisk{TargetLocations: tc.locations}
+		if got := hasTargetLocation(vdisk, tc.target); got != tc.want {
+			t.Errorf("hasTargetLocation(%q, %q) = %t", tc.locations, tc.target, got)
+		}
+	}
+}
+
+func TestRetryPolicyBackoff(t *testing.T) {
+	p := RetryPolicy{MinBackoff: time.Second, MaxBackoff: 5 * time.Second}
+
+	expected := []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second, 5 * time.Second}
+	for i, want := range expected {
+		if got := p.backoff(i + 1); got != want {
+			t.Fatalf("attempt %d: expected %s, got %s", i+1, want, got)
+		}
+	}
+
+	p.Jitter = true
+	for i := 1; i < 5; i++ {
+		if got := p.backoff(i); got < time.Second/2 || got > 5*time.Second {
+			t.Fatalf("attempt %d: jittered backoff out of range: %s", i, got)
+		}
+	}
+}
+
+func TestClientRetriesStopAtDeadline(t *testing.T) {
+	calls := 0
+	c, closer := newRetryTestClient(t, func(w http.ResponseWriter, reqType string) {
+		calls++
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})
+	defer closer()
+
+	c.Retry.MaxAttempts = 100
+	c.Retry.MinBackoff = 20 * time.Millisecond
+	c.Retry.MaxBackoff = 20 * time.Millisecond
+	c.Retry.Jitter = false
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	if _, err := c.VirtualDiskDetails(ctx, "d"); err == nil {
+		t.Fatal("expected the deadline to end the retries")
+	}
+	if calls >= 100 {
+		t.Fatalf("expected the deadline to cut the retries short, got %d calls", calls)
+	}
+}
@@ -0,0 +1,53 @@
+package client
+
+import "context"
+
+type logoutParams struct{}
+
+func (logoutParams) Type() string     { return "Logout" }
+func (logoutParams) Category() string { return "UserManagement" }
+
+// session returns the cached session ID, logging in first if there is none.
+// Concurrent callers wait for a single Login instead of each starting one.
+func (c *Client) session(ctx context.Context) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.sessionID != "" {
+		return c.sessionID, nil
+	}
+
+	sessionID, err := c.Login(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	c.sessionID = sessionID
+	return sessionID, nil
+}
+
+// invalidate drops the cached session, unless another caller has already
+// replaced it with a fresh one.
+func (c *Client) invalidate(sessionID string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.sessionID == sessionID {
+		c.sessionID = ""
+	}
+}
+
+// Logout ends the cached session, if any.
+func (c *Client) Logout(ctx context.Context) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.sessionID == "" {
+		return nil
+	}
+
+	resp := Response{}
+	err := c.do(ctx, &logoutParams{}, c.sessionID, &resp)
+	c.sessionID = ""
+	return err
+}
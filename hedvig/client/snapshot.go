@@ -0,0 +1,174 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Snapshot describes a point-in-time snapshot of a vdisk.
+type Snapshot struct {
+	Name        string `json:"name"`
+	VirtualDisk string `json:"vDiskName"`
+	// CreationTime is in milliseconds since the epoch.
+	CreationTime int64        `json:"creationTime"`
+	Size         ReportedSize `json:"size"`
+}
+
+// Created returns the creation time of the snapshot.
+func (s *Snapshot) Created() time.Time {
+	return time.Unix(0, s.CreationTime*int64(time.Millisecond)).UTC()
+}
+
+type CreateSnapshot struct {
+	VirtualDisk string `json:"virtualDisk"`
+}
+
+func (CreateSnapshot) Type() string     { return "CreateSnapshot" }
+func (CreateSnapshot) Category() string { return categoryVirtualDisk }
+
+type CreateSnapshotResponse struct {
+	Response
+	Result Snapshot `json:"result"`
+}
+
+// CreateSnapshot snapshots a vdisk. Snapshots are named by the cluster; the
+// name is returned in the response.
+func (c *Client) CreateSnapshot(ctx context.Context, req *CreateSnapshot) (*CreateSnapshotResponse, error) {
+	before, err := c.ListSnapshots(ctx, req.VirtualDisk)
+	if err != nil {
+		return nil, err
+	}
+
+	// The snapshot req creates is the only one new since before and taken
+	// no earlier than requested. Creation times are in milliseconds.
+	requested := time.Now().Truncate(time.Millisecond)
+
+	resp := &CreateSnapshotResponse{}
+	err = c.mutate(ctx, req, resp, func() (bool, error) {
+		after, err := c.ListSnapshots(ctx, req.VirtualDisk)
+		if err != nil {
+			return false, err
+		}
+		var created []Snapshot
+		for _, snapshot := range after.Result {
+			if before.find(snapshot.Name) == nil && !snapshot.Created().Before(requested) {
+				created = append(created, snapshot)
+			}
+		}
+		switch len(created) {
+		case 0:
+			return false, nil
+		case 1:
+			*resp = CreateSnapshotResponse{Response: appliedResponse(req), Result: created[0]}
+			return true, nil
+		}
+		return false, fmt.Errorf("%d snapshots of %s were taken since the request, cannot tell which it created",
+			len(created), req.VirtualDisk)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+type ListSnapshots struct {
+	VirtualDisk string `json:"virtualDisk"`
+}
+
+func (ListSnapshots) Type() string     { return "ListSnapshots" }
+func (ListSnapshots) Category() string { return categoryVirtualDisk }
+
+type ListSnapshotsResponse struct {
+	Response
+	Result []Snapshot `json:"result"`
+}
+
+func (r *ListSnapshotsResponse) find(name string) *Snapshot {
+	for i := range r.Result {
+		if r.Result[i].Name == name {
+			return &r.Result[i]
+		}
+	}
+	return nil
+}
+
+// ListSnapshots lists the snapshots of a vdisk.
+func (c *Client) ListSnapshots(ctx context.Context, vdisk string) (*ListSnapshotsResponse, error) {
+	resp := &ListSnapshotsResponse{}
+	if err := c.read(ctx, &ListSnapshots{VirtualDisk: vdisk}, resp); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// SnapshotDetails returns a snapshot of vdisk, or a not found error.
+func (c *Client) SnapshotDetails(ctx context.Context, vdisk, name string) (*Snapshot, error) {
+	resp, err := c.ListSnapshots(ctx, vdisk)
+	if err != nil {
+		return nil, err
+	}
+	if snapshot := resp.find(name); snapshot != nil {
+		return snapshot, nil
+	}
+	return nil, itemError(&resp.Response, &ListSnapshots{VirtualDisk: vdisk}, "error", "Snapshot "+name+" couldn't be found")
+}
+
+type DeleteSnapshot struct {
+	VirtualDisk string   `json:"virtualDisk"`
+	Snapshots   []string `json:"snapshots"`
+}
+
+func (DeleteSnapshot) Type() string     { return "DeleteSnapshot" }
+func (DeleteSnapshot) Category() string { return categoryVirtualDisk }
+
+type DeleteSnapshotResponse struct {
+	Response
+	Result []DiskResult `json:"result"`
+}
+
+func (c *Client) DeleteSnapshot(ctx context.Context, req *DeleteSnapshot) (*DeleteSnapshotResponse, error) {
+	resp := &DeleteSnapshotResponse{}
+	err := c.mutate(ctx, req, resp, func() (bool, error) {
+		list, err := c.ListSnapshots(ctx, req.VirtualDisk)
+		if err != nil {
+			return false, err
+		}
+		for _, name := range req.Snapshots {
+			if list.find(name) != nil {
+				return false, nil
+			}
+		}
+		*resp = DeleteSnapshotResponse{Response: appliedResponse(req), Result: appliedDisks(req.Snapshots...)}
+		return true, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp, checkDiskResults(&resp.Response, req, resp.Result)
+}
+
+// RollbackVirtualDisk reverts the contents of a vdisk to one of its
+// snapshots. The disk must not be exported.
+type RollbackVirtualDisk struct {
+	VirtualDisk string `json:"virtualDisk"`
+	Snapshot    string `json:"snapshot"`
+}
+
+func (RollbackVirtualDisk) Type() string     { return "RollbackVirtualDisk" }
+func (RollbackVirtualDisk) Category() string { return categoryVirtualDisk }
+
+type RollbackVirtualDiskResponse struct {
+	Response
+	Result []DiskResult `json:"result"`
+}
+
+func (c *Client) RollbackVirtualDisk(ctx context.Context, req *RollbackVirtualDisk) (*RollbackVirtualDiskResponse, error) {
+	resp := &RollbackVirtualDiskResponse{}
+	// Rolling back to the same snapshot again is harmless, so a failed
+	// call is simply replayed.
+	if err := c.mutate(ctx, req, resp, nil); err != nil {
+		return nil, err
+	}
+	return resp, checkDiskResults(&resp.Response, req, resp.Result)
+}
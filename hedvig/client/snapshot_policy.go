@@ -0,0 +1,125 @@
+package client
+
+import "context"
+
+// SnapshotPolicy takes snapshots of a set of vdisks on a schedule, keeping
+// the most recent Retention of them. Either IntervalMinutes or Cron is set.
+type SnapshotPolicy struct {
+	Name            string   `json:"name"`
+	IntervalMinutes int      `json:"intervalMinutes,omitempty"`
+	Cron            string   `json:"cron,omitempty"`
+	Retention       int      `json:"retention"`
+	VirtualDisks    []string `json:"virtualDisks"`
+}
+
+type AddSnapshotPolicy struct {
+	SnapshotPolicy
+}
+
+func (AddSnapshotPolicy) Type() string     { return "AddSnapshotPolicy" }
+func (AddSnapshotPolicy) Category() string { return categoryVirtualDisk }
+
+func (c *Client) AddSnapshotPolicy(ctx context.Context, req *AddSnapshotPolicy) (*Response, error) {
+	resp := &Response{}
+	err := c.mutate(ctx, req, resp, func() (bool, error) {
+		_, err := c.SnapshotPolicyDetails(ctx, req.Name)
+		if IsNotFound(err) {
+			return false, nil
+		}
+		if err != nil {
+			return false, err
+		}
+		*resp = appliedResponse(req)
+		return true, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+type SnapshotPolicyDetails struct {
+	Name string `json:"name"`
+}
+
+func (SnapshotPolicyDetails) Type() string     { return "SnapshotPolicyDetails" }
+func (SnapshotPolicyDetails) Category() string { return categoryVirtualDisk }
+
+type SnapshotPolicyDetailsResponse struct {
+	Response
+	Result SnapshotPolicy `json:"result"`
+}
+
+func (c *Client) SnapshotPolicyDetails(ctx context.Context, name string) (*SnapshotPolicyDetailsResponse, error) {
+	resp := &SnapshotPolicyDetailsResponse{}
+	if err := c.read(ctx, &SnapshotPolicyDetails{Name: name}, resp); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// UpdateSnapshotPolicy replaces the schedule, retention and vdisks of an
+// existing policy.
+type UpdateSnapshotPolicy struct {
+	SnapshotPolicy
+}
+
+func (UpdateSnapshotPolicy) Type() string     { return "UpdateSnapshotPolicy" }
+func (UpdateSnapshotPolicy) Category() string { return categoryVirtualDisk }
+
+func (c *Client) UpdateSnapshotPolicy(ctx context.Context, req *UpdateSnapshotPolicy) (*Response, error) {
+	resp := &Response{}
+	err := c.mutate(ctx, req, resp, func() (bool, error) {
+		details, err := c.SnapshotPolicyDetails(ctx, req.Name)
+		if err != nil {
+			return false, err
+		}
+		if !details.Result.equal(&req.SnapshotPolicy) {
+			return false, nil
+		}
+		*resp = appliedResponse(req)
+		return true, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+type DeleteSnapshotPolicy struct {
+	Name string `json:"name"`
+}
+
+func (DeleteSnapshotPolicy) Type() string     { return "DeleteSnapshotPolicy" }
+func (DeleteSnapshotPolicy) Category() string { return categoryVirtualDisk }
+
+func (c *Client) DeleteSnapshotPolicy(ctx context.Context, req *DeleteSnapshotPolicy) (*Response, error) {
+	resp := &Response{}
+	err := c.mutate(ctx, req, resp, func() (bool, error) {
+		_, err := c.SnapshotPolicyDetails(ctx, req.Name)
+		if !IsNotFound(err) {
+			return false, err
+		}
+		*resp = appliedResponse(req)
+		return true, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+func (p *SnapshotPolicy) equal(o *SnapshotPolicy) bool {
+	if p.Name != o.Name || p.IntervalMinutes != o.IntervalMinutes || p.Cron != o.Cron || p.Retention != o.Retention {
+		return false
+	}
+	if len(p.VirtualDisks) != len(o.VirtualDisks) {
+		return false
+	}
+	for _, vdisk := range o.VirtualDisks {
+		if !contains(p.VirtualDisks, vdisk) {
+			return false
+		}
+	}
+	return true
+}
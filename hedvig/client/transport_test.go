@@ -0,0 +1,71 @@
+package client
+
+import (
+	"context"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"testing"
+)
+
+func TestNewHTTPClientCABundle(t *testing.T) {
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"status":"ok","result":{"sessionId":"abc"}}`)
+	}))
+	defer ts.Close()
+
+	f, err := ioutil.TempFile("", "hedvig-ca")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+
+	pem.Encode(f, &pem.Block{Type: "CERTIFICATE", Bytes: ts.Certificate().Raw})
+	f.Close()
+
+	u, _ := url.Parse(ts.URL)
+
+	cases := map[string]struct {
+		cfg     TransportConfig
+		wantErr bool
+	}{
+		"system roots": {TransportConfig{}, true},
+		"ca bundle":    {TransportConfig{CAFile: f.Name()}, false},
+		"insecure":     {TransportConfig{InsecureSkipVerify: true}, false},
+	}
+
+	for name, tc := range cases {
+		httpClient, err := NewHTTPClient(&tc.cfg)
+		if err != nil {
+			t.Fatalf("%s: %s", name, err)
+		}
+
+		c := New([]string{u.Host}, "admin", "admin")
+		c.Scheme = "https"
+		c.HTTPClient = httpClient
+		c.Retry.MaxAttempts = 1
+
+		_, err = c.Login(context.Background())
+		if (err != nil) != tc.wantErr {
+			t.Fatalf("%s: unexpected error state: %v", name, err)
+		}
+	}
+}
+
+func TestNewHTTPClientInvalidConfig(t *testing.T) {
+	cases := map[string]TransportConfig{
+		"missing ca":       {CAFile: "/nonexistent/ca.pem"},
+		"cert without key": {ClientCertFile: "cert.pem"},
+		"bad proxy":        {ProxyURL: "://proxy"},
+	}
+
+	for name, cfg := range cases {
+		if _, err := NewHTTPClient(&cfg); err == nil {
+			t.Fatalf("%s: expected an error", name)
+		}
+	}
+}
@@ -0,0 +1,459 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const categoryVirtualDisk = "VirtualDiskManagement"
+
+// Size is a capacity as understood by the Hedvig API.
+type Size struct {
+	Unit  string `json:"unit"`
+	Value int    `json:"value"`
+}
+
+// Bytes is the size in bytes, or 0 if its unit is unknown.
+func (s Size) Bytes() int64 {
+	return int64(s.Value) * UnitBytes(s.Unit)
+}
+
+// UnitBytes returns the number of bytes in a size unit. Hedvig units are
+// binary, so a GB is 2^30 bytes. Unknown units yield 0.
+func UnitBytes(unit string) int64 {
+	switch strings.ToUpper(unit) {
+	case "MB":
+		return 1 << 20
+	case "GB":
+		return 1 << 30
+	case "TB":
+		return 1 << 40
+	case "PB":
+		return 1 << 50
+	}
+	return 0
+}
+
+// ReportedSize is a capacity as reported by the Hedvig API.
+type ReportedSize struct {
+	Units string `json:"units"`
+	Value int    `json:"value"`
+}
+
+// Bytes is the size in bytes, or 0 if its unit is unknown.
+func (s ReportedSize) Bytes() int64 {
+	return int64(s.Value) * UnitBytes(s.Units)
+}
+
+type AddVirtualDisk struct {
+	Name                string `json:"name"`
+	Size                Size   `json:"size"`
+	DiskType            string `json:"diskType"`
+	Residence           string `json:"residence"`
+	ReplicationFactor   int    `json:"replicationFactor"`
+	Deduplication       bool   `json:"deduplication"`
+	Compressed          bool   `json:"compressed"`
+	BlockSize           int    `json:"blockSize"`
+	Scsi3pr             bool   `json:"scsi3pr"`
+	CacheEnabled        bool   `json:"cacheEnabled"`
+	ReplicationPolicy   string `json:"replicationPolicy"`
+	ClusteredFileSystem bool   `json:"clusteredFileSystem"`
+	Encryption          bool   `json:"encryption"`
+	Description         string `json:"description"`
+}
+
+func (AddVirtualDisk) Type() string     { return "AddVirtualDisk" }
+func (AddVirtualDisk) Category() string { return categoryVirtualDisk }
+
+// DiskResult is the per-disk outcome reported by bulk vdisk operations.
+type DiskResult struct {
+	Name    string `json:"name"`
+	Message string `json:"message"`
+	Status  string `json:"status"`
+}
+
+type AddVirtualDiskResponse struct {
+	Response
+	Result []DiskResult `json:"result"`
+}
+
+func (c *Client) AddVirtualDisk(ctx context.Context, req *AddVirtualDisk) (*AddVirtualDiskResponse, error) {
+	resp := &AddVirtualDiskResponse{}
+	err := c.mutate(ctx, req, resp, func() (bool, error) {
+		details, err := c.VirtualDiskDetails(ctx, req.Name)
+		if IsNotFound(err) {
+			return false, nil
+		}
+		if err != nil {
+			return false, err
+		}
+		// A disk of that name with other settings was not created by req.
+		if conflicts := req.Conflicts(&details.Result); len(conflicts) > 0 {
+			return false, fmt.Errorf("vdisk %q exists with different settings", req.Name)
+		}
+		*resp = AddVirtualDiskResponse{Response: appliedResponse(req), Result: appliedDisks(req.Name)}
+		return true, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp, checkDiskResults(&resp.Response, req, resp.Result)
+}
+
+// Conflict is a setting of an existing disk that differs from the one
+// requested for it.
+type Conflict struct {
+	Setting           string
+	Requested, Actual interface{}
+}
+
+// Conflicts lists the settings of vdisk that differ from the request.
+func (req *AddVirtualDisk) Conflicts(vdisk *VirtualDisk) []Conflict {
+	settings := []struct {
+		setting           string
+		requested, actual interface{}
+		equal             bool
+	}{
+		{"diskType", req.DiskType, diskType(vdisk.DiskType), strings.EqualFold(req.DiskType, diskType(vdisk.DiskType))},
+		{"size", fmt.Sprintf("%d %s", req.Size.Value, req.Size.Unit), fmt.Sprintf("%d %s", vdisk.Size.Value, vdisk.Size.Units), req.Size.Bytes() == vdisk.SizeBytes()},
+		{"residence", req.Residence, vdisk.Residence, strings.EqualFold(req.Residence, vdisk.Residence)},
+		{"replicationFactor", req.ReplicationFactor, vdisk.ReplicationFactor, req.ReplicationFactor == vdisk.ReplicationFactor},
+		{"replicationPolicy", req.ReplicationPolicy, replicationPolicy(vdisk.ReplicationPolicy), sameReplicationPolicy(req.ReplicationPolicy, vdisk.ReplicationPolicy)},
+		{"deduplication", req.Deduplication, vdisk.Deduplication, req.Deduplication == vdisk.Deduplication},
+		{"compressed", req.Compressed, vdisk.Compressed, req.Compressed == vdisk.Compressed},
+		{"blockSize", req.BlockSize, vdisk.BlockSize, req.BlockSize == vdisk.BlockSize},
+		{"clusteredFileSystem", req.ClusteredFileSystem, vdisk.ClusteredFileSystem, req.ClusteredFileSystem == vdisk.ClusteredFileSystem},
+		{"scsi3pr", req.Scsi3pr, vdisk.Scsi3pr, req.Scsi3pr == vdisk.Scsi3pr},
+		{"cacheEnabled", req.CacheEnabled, vdisk.CacheEnabled, req.CacheEnabled == vdisk.CacheEnabled},
+		{"encryption", req.Encryption, vdisk.Encryption, req.Encryption == vdisk.Encryption},
+		{"description", strconv.Quote(req.Description), strconv.Quote(vdisk.Description), req.Description == vdisk.Description},
+	}
+
+	conflicts := []Conflict{}
+	for _, s := range settings {
+		if !s.equal {
+			conflicts = append(conflicts, Conflict{Setting: s.setting, Requested: s.requested, Actual: s.actual})
+		}
+	}
+	return conflicts
+}
+
+// diskType maps the disk type reported by the cluster to the one requested.
+func diskType(reported string) string {
+	if reported == "NFS_MASTER_DISK" {
+		return "NFS"
+	}
+	return reported
+}
+
+// CloneVirtualDisk creates a disk as a copy of another disk, or of one of
+// its snapshots. The clone inherits the settings of its source.
+type CloneVirtualDisk struct {
+	Name        string `json:"name"`
+	Source      string `json:"sourceVirtualDisk"`
+	Snapshot    string `json:"snapshot,omitempty"`
+	Description string `json:"description"`
+}
+
+func (CloneVirtualDisk) Type() string     { return "CloneVirtualDisk" }
+func (CloneVirtualDisk) Category() string { return categoryVirtualDisk }
+
+type CloneVirtualDiskResponse struct {
+	Response
+	Result []DiskResult `json:"result"`
+}
+
+func (c *Client) CloneVirtualDisk(ctx context.Context, req *CloneVirtualDisk) (*CloneVirtualDiskResponse, error) {
+	resp := &CloneVirtualDiskResponse{}
+	err := c.mutate(ctx, req, resp, func() (bool, error) {
+		details, err := c.VirtualDiskDetails(ctx, req.Name)
+		if IsNotFound(err) {
+			return false, nil
+		}
+		if err != nil {
+			return false, err
+		}
+		source, err := c.VirtualDiskDetails(ctx, req.Source)
+		if err != nil {
+			return false, err
+		}
+		// A disk of that name without the settings of the source was not
+		// created by req.
+		clone := source.Result.request()
+		clone.Name, clone.Description = req.Name, req.Description
+		if req.Snapshot != "" {
+			// The snapshot may be smaller than the source is now.
+			clone.Size = details.Result.request().Size
+		}
+		if conflicts := clone.Conflicts(&details.Result); len(conflicts) > 0 {
+			return false, fmt.Errorf("vdisk %q exists and is not a clone of %q", req.Name, req.Source)
+		}
+		*resp = CloneVirtualDiskResponse{Response: appliedResponse(req), Result: appliedDisks(req.Name)}
+		return true, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp, checkDiskResults(&resp.Response, req, resp.Result)
+}
+
+type VirtualDiskDetails struct {
+	VirtualDisk string `json:"virtualDisk"`
+}
+
+func (VirtualDiskDetails) Type() string     { return "VirtualDiskDetails" }
+func (VirtualDiskDetails) Category() string { return categoryVirtualDisk }
+
+// VirtualDisk describes a vdisk as reported by VirtualDiskDetails.
+type VirtualDisk struct {
+	VDiskName           string       `json:"vDiskName"`
+	Size                ReportedSize `json:"size"`
+	DiskType            string       `json:"diskType"`
+	Residence           string       `json:"residence"`
+	ReplicationFactor   int          `json:"replicationFactor"`
+	ReplicationPolicy   string       `json:"replicationPolicy"`
+	Deduplication       bool         `json:"deduplication"`
+	Compressed          bool         `json:"compressed"`
+	BlockSize           int          `json:"blockSize"`
+	ClusteredFileSystem bool         `json:"clusteredFileSystem"`
+	Scsi3pr             bool         `json:"scsi3pr"`
+	CacheEnabled        bool         `json:"cacheEnabled"`
+	Encryption          bool         `json:"encryption"`
+	Description         string       `json:"description"`
+	TargetLocations     []string     `json:"targetLocations"`
+	// Migrating is set while the disk moves to a new residence or
+	// replication setting.
+	Migrating bool `json:"migrationInProgress"`
+}
+
+// request returns the AddVirtualDisk that creates a disk like v.
+func (v *VirtualDisk) request() *AddVirtualDisk {
+	return &AddVirtualDisk{
+		Name:                v.VDiskName,
+		Size:                Size{Unit: v.Size.Units, Value: v.Size.Value},
+		DiskType:            diskType(v.DiskType),
+		Residence:           v.Residence,
+		ReplicationFactor:   v.ReplicationFactor,
+		Deduplication:       v.Deduplication,
+		Compressed:          v.Compressed,
+		BlockSize:           v.BlockSize,
+		Scsi3pr:             v.Scsi3pr,
+		CacheEnabled:        v.CacheEnabled,
+		ReplicationPolicy:   replicationPolicy(v.ReplicationPolicy),
+		ClusteredFileSystem: v.ClusteredFileSystem,
+		Encryption:          v.Encryption,
+		Description:         v.Description,
+	}
+}
+
+// SizeBytes is the size of the disk in bytes, or 0 if it was reported in an
+// unknown unit.
+func (v *VirtualDisk) SizeBytes() int64 {
+	return v.Size.Bytes()
+}
+
+type VirtualDiskDetailsResponse struct {
+	Response
+	Result VirtualDisk `json:"result"`
+}
+
+func (c *Client) VirtualDiskDetails(ctx context.Context, name string) (*VirtualDiskDetailsResponse, error) {
+	resp := &VirtualDiskDetailsResponse{}
+	if err := c.read(ctx, &VirtualDiskDetails{VirtualDisk: name}, resp); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+type ResizeDisks struct {
+	VirtualDisks []string `json:"virtualDisks"`
+	Size         Size     `json:"size"`
+}
+
+func (ResizeDisks) Type() string     { return "ResizeDisks" }
+func (ResizeDisks) Category() string { return categoryVirtualDisk }
+
+type ResizeDisksResponse struct {
+	Response
+	Result []DiskResult `json:"result"`
+}
+
+func (c *Client) ResizeDisks(ctx context.Context, req *ResizeDisks) (*ResizeDisksResponse, error) {
+	resp := &ResizeDisksResponse{}
+	err := c.mutate(ctx, req, resp, func() (bool, error) {
+		for _, name := range req.VirtualDisks {
+			details, err := c.VirtualDiskDetails(ctx, name)
+			if err != nil {
+				return false, err
+			}
+			if details.Result.SizeBytes() != req.Size.Bytes() {
+				return false, nil
+			}
+		}
+		*resp = ResizeDisksResponse{Response: appliedResponse(req), Result: appliedDisks(req.VirtualDisks...)}
+		return true, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp, checkDiskResults(&resp.Response, req, resp.Result)
+}
+
+// UpdateVirtualDisk changes the settings of a disk that may be modified
+// while it is in use. Settings left nil are not changed.
+type UpdateVirtualDisk struct {
+	VirtualDisk  string  `json:"virtualDisk"`
+	Description  *string `json:"description,omitempty"`
+	CacheEnabled *bool   `json:"cacheEnabled,omitempty"`
+}
+
+func (UpdateVirtualDisk) Type() string     { return "UpdateVirtualDisk" }
+func (UpdateVirtualDisk) Category() string { return categoryVirtualDisk }
+
+type UpdateVirtualDiskResponse struct {
+	Response
+	Result []DiskResult `json:"result"`
+}
+
+func (c *Client) UpdateVirtualDisk(ctx context.Context, req *UpdateVirtualDisk) (*UpdateVirtualDiskResponse, error) {
+	resp := &UpdateVirtualDiskResponse{}
+	err := c.mutate(ctx, req, resp, func() (bool, error) {
+		details, err := c.VirtualDiskDetails(ctx, req.VirtualDisk)
+		if err != nil {
+			return false, err
+		}
+		if req.Description != nil && details.Result.Description != *req.Description {
+			return false, nil
+		}
+		if req.CacheEnabled != nil && details.Result.CacheEnabled != *req.CacheEnabled {
+			return false, nil
+		}
+		*resp = UpdateVirtualDiskResponse{Response: appliedResponse(req), Result: appliedDisks(req.VirtualDisk)}
+		return true, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp, checkDiskResults(&resp.Response, req, resp.Result)
+}
+
+// MigrateVirtualDisk moves a disk to another residence or replication
+// setting while it stays online. Settings left empty are not changed. The
+// data is moved in the background; VirtualDiskDetails reports Migrating until
+// it is done.
+type MigrateVirtualDisk struct {
+	VirtualDisk       string `json:"virtualDisk"`
+	Residence         string `json:"residence,omitempty"`
+	ReplicationFactor int    `json:"replicationFactor,omitempty"`
+	ReplicationPolicy string `json:"replicationPolicy,omitempty"`
+}
+
+func (MigrateVirtualDisk) Type() string     { return "MigrateVirtualDisk" }
+func (MigrateVirtualDisk) Category() string { return categoryVirtualDisk }
+
+type MigrateVirtualDiskResponse struct {
+	Response
+	Result []DiskResult `json:"result"`
+}
+
+func (c *Client) MigrateVirtualDisk(ctx context.Context, req *MigrateVirtualDisk) (*MigrateVirtualDiskResponse, error) {
+	resp := &MigrateVirtualDiskResponse{}
+	err := c.mutate(ctx, req, resp, func() (bool, error) {
+		details, err := c.VirtualDiskDetails(ctx, req.VirtualDisk)
+		if err != nil {
+			return false, err
+		}
+		if !req.Reached(&details.Result) {
+			return false, nil
+		}
+		*resp = MigrateVirtualDiskResponse{Response: appliedResponse(req), Result: appliedDisks(req.VirtualDisk)}
+		return true, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp, checkDiskResults(&resp.Response, req, resp.Result)
+}
+
+// Reached reports whether a disk has the settings requested by a migration,
+// whether or not its data has finished moving.
+func (req *MigrateVirtualDisk) Reached(vdisk *VirtualDisk) bool {
+	if req.Residence != "" && !strings.EqualFold(req.Residence, vdisk.Residence) {
+		return false
+	}
+	if req.ReplicationFactor != 0 && req.ReplicationFactor != vdisk.ReplicationFactor {
+		return false
+	}
+	if req.ReplicationPolicy != "" && !sameReplicationPolicy(req.ReplicationPolicy, vdisk.ReplicationPolicy) {
+		return false
+	}
+	return true
+}
+
+// sameReplicationPolicy compares policies, allowing for RackUnaware being
+// reported for Agnostic.
+func sameReplicationPolicy(a, b string) bool {
+	return strings.EqualFold(replicationPolicy(a), replicationPolicy(b))
+}
+
+func replicationPolicy(p string) string {
+	if strings.EqualFold(p, "RackUnaware") {
+		return "Agnostic"
+	}
+	return p
+}
+
+type DeleteVDisk struct {
+	VirtualDisks []string `json:"virtualDisks"`
+}
+
+func (DeleteVDisk) Type() string     { return "DeleteVDisk" }
+func (DeleteVDisk) Category() string { return categoryVirtualDisk }
+
+type DeleteVDiskResponse struct {
+	Response
+	Result []DiskResult `json:"result"`
+}
+
+func (c *Client) DeleteVDisk(ctx context.Context, req *DeleteVDisk) (*DeleteVDiskResponse, error) {
+	resp := &DeleteVDiskResponse{}
+	err := c.mutate(ctx, req, resp, func() (bool, error) {
+		for _, name := range req.VirtualDisks {
+			_, err := c.VirtualDiskDetails(ctx, name)
+			if IsNotFound(err) {
+				continue
+			}
+			return false, err
+		}
+		*resp = DeleteVDiskResponse{Response: appliedResponse(req), Result: appliedDisks(req.VirtualDisks...)}
+		return true, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp, checkDiskResults(&resp.Response, req, resp.Result)
+}
+
+func appliedDisks(names ...string) []DiskResult {
+	results := make([]DiskResult, 0, len(names))
+	for _, name := range names {
+		results = append(results, DiskResult{Name: name, Status: "ok"})
+	}
+	return results
+}
+
+// checkDiskResults turns the first failed per-disk result into an error.
+func checkDiskResults(resp *Response, req Request, results []DiskResult) error {
+	if len(results) < 1 {
+		return itemError(resp, req, "error", resp.Message)
+	}
+
+	for _, result := range results {
+		if result.Status != "ok" {
+			return itemError(resp, req, result.Status, result.Message)
+		}
+	}
+	return nil
+}
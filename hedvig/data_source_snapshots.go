@@ -0,0 +1,73 @@
+package hedvig
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/hashicorp/terraform/helper/schema"
+)
+
+func dataSourceSnapshots() *schema.Resource {
+	return &schema.Resource{
+		Read: dataSourceSnapshotsRead,
+
+		Timeouts: &schema.ResourceTimeout{
+			Read: schema.DefaultTimeout(5 * time.Minute),
+		},
+
+		Schema: map[string]*schema.Schema{
+			"vdisk": {
+				Type:     schema.TypeString,
+				Required: true,
+			},
+			"snapshots": {
+				Type:     schema.TypeList,
+				Computed: true,
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"name": {
+							Type:     schema.TypeString,
+							Computed: true,
+						},
+						"created_at": {
+							Type:     schema.TypeString,
+							Computed: true,
+						},
+						"size_bytes": {
+							Type:     schema.TypeInt,
+							Computed: true,
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func dataSourceSnapshotsRead(d *schema.ResourceData, meta interface{}) error {
+	ctx, cancel := context.WithTimeout(context.Background(), d.Timeout(schema.TimeoutRead))
+	defer cancel()
+
+	vdisk := d.Get("vdisk").(string)
+	resp, err := meta.(*HedvigClient).ListSnapshots(ctx, vdisk)
+	if err != nil {
+		return fmt.Errorf("Error listing snapshots of vdisk %q: %s", vdisk, err)
+	}
+
+	snapshots := make([]map[string]interface{}, 0, len(resp.Result))
+	for _, snapshot := range resp.Result {
+		sizeBytes, err := intBytes(snapshot.Size.Bytes())
+		if err != nil {
+			return fmt.Errorf("Error reading the size of snapshot %q of vdisk %q: %s", snapshot.Name, vdisk, err)
+		}
+		snapshots = append(snapshots, map[string]interface{}{
+			"name":       snapshot.Name,
+			"created_at": snapshot.Created().Format(time.RFC3339),
+			"size_bytes": sizeBytes,
+		})
+	}
+
+	d.SetId(vdisk)
+	return d.Set("snapshots", snapshots)
+}
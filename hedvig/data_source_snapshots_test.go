@@ -0,0 +1,46 @@
+package hedvig
+
+import (
+	"testing"
+
+	"github.com/hashicorp/terraform/helper/resource"
+)
+
+func TestUnitHedvigSnapshotsDataSource_basic(t *testing.T) {
+	s := testUnitServer()
+	defer s.Close()
+
+	resource.UnitTest(t, resource.TestCase{
+		Providers: testAccProviders,
+		Steps: []resource.TestStep{
+			{
+				Config: testUnitConfig(s, `
+resource "hedvig_vdisk" "test" {
+  name = "unit-snapshots-vdisk"
+  size = 9
+  type = "BLOCK"
+}
+
+resource "hedvig_snapshot" "first" {
+  vdisk = "${hedvig_vdisk.test.name}"
+}
+
+resource "hedvig_snapshot" "second" {
+  vdisk = "${hedvig_snapshot.first.vdisk}"
+}
+
+data "hedvig_snapshots" "test" {
+  vdisk = "${hedvig_snapshot.second.vdisk}"
+}
+`),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("data.hedvig_snapshots.test", "id", "unit-snapshots-vdisk"),
+					resource.TestCheckResourceAttr("data.hedvig_snapshots.test", "snapshots.#", "2"),
+					resource.TestCheckResourceAttrPair("data.hedvig_snapshots.test", "snapshots.0.name", "hedvig_snapshot.first", "name"),
+					resource.TestCheckResourceAttrPair("data.hedvig_snapshots.test", "snapshots.1.name", "hedvig_snapshot.second", "name"),
+					resource.TestCheckResourceAttr("data.hedvig_snapshots.test", "snapshots.1.size_bytes", "9663676416"),
+				),
+			},
+		},
+	})
+}
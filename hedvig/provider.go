@@ -14,46 +14,38 @@ Modified by Katrina for Hedvig
 package hedvig
 
 import (
-	"encoding/json"
+	"context"
 	"errors"
 	"fmt"
-	"io/ioutil"
 	"log"
-	"net/http"
-	"net/url"
+	"strings"
+	"time"
 
 	"github.com/hashicorp/terraform/helper/schema"
+	"github.com/hashicorp/terraform/helper/validation"
 	"github.com/hashicorp/terraform/terraform"
+	"github.com/terraform-providers/terraform-provider-hedvig/hedvig/client"
 )
 
-type LoginResponse struct {
-	Result struct {
-		Datacenters []interface{} `json:"datacenters"`
-		DisplayName string        `json:"displayName"`
-		Roles       struct {
-			Hedvig string `json:"Hedvig"`
-		} `json:"roles"`
-		Dualdc        bool   `json:"dualdc"`
-		SessionID     string `json:"sessionId"`
-		UserName      string `json:"userName"`
-		PrimaryTenant string `json:"primaryTenant"`
-	} `json:"result"`
-	RequestID string `json:"requestId"`
-	Type      string `json:"type"`
-	Status    string `json:"status"`
-}
-
 type HedvigClient struct {
-	Username string
-	Password string
-	Node     string
+	*client.Client
+
+	// CapacityHeadroom is the share of a residence tier, in percent, that
+	// must remain free after a vdisk is created or grown.
+	CapacityHeadroom int
+	// CapacityCheck is what happens when it would not: "error", "warn" or
+	// "off".
+	CapacityCheck string
+	// NamePrefix is required at the start of the names of new vdisks.
+	NamePrefix string
 }
 
 func Provider() terraform.ResourceProvider {
 	return &schema.Provider{
-		Schema:        providerSchema(),
-		ResourcesMap:  providerResources(),
-		ConfigureFunc: providerConfigure,
+		Schema:         providerSchema(),
+		ResourcesMap:   providerResources(),
+		DataSourcesMap: providerDataSources(),
+		ConfigureFunc:  providerConfigure,
 	}
 }
 
@@ -70,67 +62,240 @@ func providerSchema() map[string]*schema.Schema {
 			DefaultFunc: schema.EnvDefaultFunc("HV_TESTPASS", ""),
 		},
 		"node": {
+			Type:        schema.TypeString,
+			Optional:    true,
+			DefaultFunc: schema.EnvDefaultFunc("HV_TESTNODE", nil),
+		},
+		"nodes": {
+			Type:     schema.TypeList,
+			Optional: true,
+			Elem:     &schema.Schema{Type: schema.TypeString},
+		},
+		"scheme": {
+			Type:         schema.TypeString,
+			Optional:     true,
+			DefaultFunc:  schema.EnvDefaultFunc("HV_SCHEME", "http"),
+			ValidateFunc: validation.StringInSlice([]string{"http", "https"}, false),
+		},
+		"ca_file": {
+			Type:     schema.TypeString,
+			Optional: true,
+		},
+		"client_cert_file": {
 			Type:     schema.TypeString,
-			Required: true,
+			Optional: true,
+		},
+		"client_key_file": {
+			Type:     schema.TypeString,
+			Optional: true,
+		},
+		"insecure_skip_verify": {
+			Type:     schema.TypeBool,
+			Optional: true,
+			Default:  false,
+		},
+		"proxy_url": {
+			Type:     schema.TypeString,
+			Optional: true,
+		},
+		"request_timeout": {
+			Type:         schema.TypeString,
+			Optional:     true,
+			Default:      "60s",
+			ValidateFunc: validateDuration,
+		},
+		"retry": {
+			Type:     schema.TypeList,
+			Optional: true,
+			MaxItems: 1,
+			Elem: &schema.Resource{
+				Schema: map[string]*schema.Schema{
+					"max_attempts": {
+						Type:         schema.TypeInt,
+						Optional:     true,
+						Default:      4,
+						ValidateFunc: validation.IntAtLeast(1),
+					},
+					"min_backoff": {
+						Type:         schema.TypeString,
+						Optional:     true,
+						Default:      "1s",
+						ValidateFunc: validateDuration,
+					},
+					"max_backoff": {
+						Type:         schema.TypeString,
+						Optional:     true,
+						Default:      "30s",
+						ValidateFunc: validateDuration,
+					},
+					"jitter": {
+						Type:     schema.TypeBool,
+						Optional: true,
+						Default:  true,
+					},
+					"retryable_statuses": {
+						Type:     schema.TypeList,
+						Optional: true,
+						Elem: &schema.Schema{
+							Type:         schema.TypeInt,
+							ValidateFunc: validation.IntBetween(400, 599),
+						},
+					},
+					"retryable_messages": {
+						Type:     schema.TypeList,
+						Optional: true,
+						Elem:     &schema.Schema{Type: schema.TypeString},
+					},
+				},
+			},
+		},
+		"capacity_headroom": {
+			Type:         schema.TypeInt,
+			Optional:     true,
+			Default:      10,
+			ValidateFunc: validation.IntBetween(0, 100),
+		},
+		"capacity_check": {
+			Type:         schema.TypeString,
+			Optional:     true,
+			Default:      "error",
+			ValidateFunc: validation.StringInSlice([]string{"error", "warn", "off"}, false),
+		},
+		"name_prefix": {
+			Type:         schema.TypeString,
+			Optional:     true,
+			ValidateFunc: validateVdiskName,
 		},
 	}
 }
 
 func providerConfigure(d *schema.ResourceData) (interface{}, error) {
-	client := HedvigClient{
-		Username: d.Get("username").(string),
-		Password: d.Get("password").(string),
-		Node:     d.Get("node").(string),
+	// Checked by validateDuration.
+	timeout, _ := time.ParseDuration(d.Get("request_timeout").(string))
+
+	httpClient, err := client.NewHTTPClient(&client.TransportConfig{
+		CAFile:             d.Get("ca_file").(string),
+		ClientCertFile:     d.Get("client_cert_file").(string),
+		ClientKeyFile:      d.Get("client_key_file").(string),
+		InsecureSkipVerify: d.Get("insecure_skip_verify").(bool),
+		ProxyURL:           d.Get("proxy_url").(string),
+		Timeout:            timeout,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	nodes := []string{}
+	if v, ok := d.GetOk("node"); ok {
+		nodes = append(nodes, v.(string))
+	}
+	for _, v := range d.Get("nodes").([]interface{}) {
+		if v.(string) != "" && v.(string) != d.Get("node").(string) {
+			nodes = append(nodes, v.(string))
+		}
+	}
+
+	if len(nodes) == 0 {
+		return nil, errors.New("One of node or nodes must be set")
+	}
+
+	if d.Get("scheme").(string) == "http" {
+		log.Printf("[WARN] Credentials for %s are sent in clear text; set scheme = \"https\"", strings.Join(nodes, ", "))
+	}
+
+	c := HedvigClient{
+		Client: client.New(
+			nodes,
+			d.Get("username").(string),
+			d.Get("password").(string),
+		),
+	}
+	c.Scheme = d.Get("scheme").(string)
+	c.HTTPClient = httpClient
+	c.CapacityHeadroom = d.Get("capacity_headroom").(int)
+	c.CapacityCheck = d.Get("capacity_check").(string)
+	c.NamePrefix = d.Get("name_prefix").(string)
+
+	if v, ok := d.GetOk("retry"); ok && v.([]interface{})[0] != nil {
+		c.Retry = expandRetryPolicy(v.([]interface{})[0].(map[string]interface{}))
 	}
 
-	return &client, nil
+	return &c, nil
 }
 
 func providerResources() map[string]*schema.Resource {
 	return map[string]*schema.Resource{
-		"hedvig_vdisk":  resourceVdisk(),
-		"hedvig_lun":    resourceLun(),
-		"hedvig_mount":  resourceMount(),
-		"hedvig_access": resourceAccess(),
+		"hedvig_vdisk":           resourceVdisk(),
+		"hedvig_lun":             resourceLun(),
+		"hedvig_mount":           resourceMount(),
+		"hedvig_access":          resourceAccess(),
+		"hedvig_snapshot":        resourceSnapshot(),
+		"hedvig_snapshot_policy": resourceSnapshotPolicy(),
+		"hedvig_vdisk_restore":   resourceVdiskRestore(),
 	}
 }
 
-func GetSessionId(d *schema.ResourceData, p *HedvigClient) (string, error) {
-	u := url.URL{}
-	u.Host = p.Node
-	u.Path = "/rest/"
-	u.Scheme = "http"
-
-	q := url.Values{}
-	q.Set("request", fmt.Sprintf("{type:Login,category:UserManagement,params:{userName:'%s',password:'%s',cluster:''}}",
-		p.Username, p.Password))
+func providerDataSources() map[string]*schema.Resource {
+	return map[string]*schema.Resource{
+		"hedvig_snapshots": dataSourceSnapshots(),
+	}
+}
 
-	u.RawQuery = q.Encode()
+func expandRetryPolicy(m map[string]interface{}) client.RetryPolicy {
+	p := client.DefaultRetryPolicy()
 
-	// TODO: remove
-	log.Printf("QUERY: %v\n", u.String())
+	p.MaxAttempts = m["max_attempts"].(int)
+	// Both durations have been checked by validateDuration.
+	p.MinBackoff, _ = time.ParseDuration(m["min_backoff"].(string))
+	p.MaxBackoff, _ = time.ParseDuration(m["max_backoff"].(string))
+	p.Jitter = m["jitter"].(bool)
 
-	resp, err := http.Get(u.String())
-	if err != nil {
-		log.Fatal(err)
+	if statuses := m["retryable_statuses"].([]interface{}); len(statuses) > 0 {
+		p.RetryableStatuses = nil
+		for _, status := range statuses {
+			p.RetryableStatuses = append(p.RetryableStatuses, status.(int))
+		}
 	}
-	body, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		log.Fatal(err)
+
+	if messages := m["retryable_messages"].([]interface{}); len(messages) > 0 {
+		p.RetryableMessages = nil
+		for _, message := range messages {
+			p.RetryableMessages = append(p.RetryableMessages, message.(string))
+		}
 	}
 
-	login := LoginResponse{}
-	err = json.Unmarshal(body, &login)
+	return p
+}
 
+func validateDuration(v interface{}, k string) (ws []string, es []error) {
+	d, err := time.ParseDuration(v.(string))
 	if err != nil {
-		return "", err
+		es = append(es, fmt.Errorf("%q: %s", k, err))
+		return
+	}
+	if d < 0 {
+		es = append(es, fmt.Errorf("%q must not be negative", k))
+	}
+	return
+}
+
+// Logout ends the cluster session held by a configured provider. It is
+// called once the plugin stops serving requests.
+func Logout(p terraform.ResourceProvider) {
+	sp, ok := p.(*schema.Provider)
+	if !ok {
+		return
 	}
 
-	if login.Status != "ok" {
-		// TODO: raise log level to ERROR
-		log.Printf("GetSessionID failed")
-		return "", errors.New(login.Status)
+	c, ok := sp.Meta().(*HedvigClient)
+	if !ok || c == nil {
+		return
 	}
 
-	return login.Result.SessionID, nil
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if err := c.Logout(ctx); err != nil {
+		log.Printf("[WARN] Error logging out of Hedvig cluster: %s", err)
+	}
 }
@@ -1,11 +1,13 @@
 package hedvig
 
 import (
+	"fmt"
 	"os"
 	"testing"
 
 	"github.com/hashicorp/terraform/helper/schema"
 	"github.com/hashicorp/terraform/terraform"
+	"github.com/terraform-providers/terraform-provider-hedvig/hedvig/client/clienttest"
 )
 
 var testAccProviders map[string]terraform.ResourceProvider
@@ -18,6 +20,12 @@ func init() {
 	}
 }
 
+func TestProvider(t *testing.T) {
+	if err := Provider().(*schema.Provider).InternalValidate(); err != nil {
+		t.Fatal(err)
+	}
+}
+
 func testAccPreCheck(t *testing.T) {
 	requiredVars := []string{"HV_TESTNODE", "HV_TESTCONT", "HV_TESTUSER",
 		"HV_TESTPASS", "HV_TESTADDR", "HV_TESTADDR2"}
@@ -37,3 +45,28 @@ func testAccPreCheck(t *testing.T) {
 		t.Fatal(err)
 	}
 }
+
+// testUnitConfig prefixes config with a provider block pointing at the fake
+// cluster s, which also holds any further providerArguments.
+func testUnitConfig(s *clienttest.Server, config string, providerArguments ...string) string {
+	return fmt.Sprintf(`
+provider "hedvig" {
+  node = "%s"
+  username = "%s"
+  password = "%s"
+%s}
+`, s.Node(), s.Username, s.Password, testUnitArguments(providerArguments)) + config
+}
+
+// testUnitArguments formats arguments as the lines of an HCL block.
+func testUnitArguments(arguments []string) string {
+	lines := ""
+	for _, argument := range arguments {
+		lines += "  " + argument + "\n"
+	}
+	return lines
+}
+
+func testUnitServer() *clienttest.Server {
+	return clienttest.NewServer("admin", "secret")
+}
@@ -43,6 +43,8 @@ resource "hedvig_vdisk" "test-access-vdisk2" {
   name = "%s"
   size = 14
   type = "NFS"
+  blocksize = "512"
+  clusteredfilesystem = true
 }
 
 resource "hedvig_lun" "test-access-lun" {
@@ -114,3 +116,51 @@ func testAccCheckHedvigAccessDestroy(n string) resource.TestCheckFunc {
 		return nil
 	}
 }
+
+func TestUnitHedvigAccess_basic(t *testing.T) {
+	s := testUnitServer()
+	defer s.Close()
+
+	resource.UnitTest(t, resource.TestCase{
+		Providers: testAccProviders,
+		CheckDestroy: func(*terraform.State) error {
+			if vdisk := s.VDisk("unit-access-vdisk"); vdisk != nil && len(vdisk.ACL) > 0 {
+				return fmt.Errorf("ACL entries still present: %v", vdisk.ACL)
+			}
+			return nil
+		},
+		Steps: []resource.TestStep{
+			{
+				Config: testUnitConfig(s, `
+resource "hedvig_vdisk" "test" {
+  name = "unit-access-vdisk"
+  size = 9
+  type = "BLOCK"
+}
+
+resource "hedvig_lun" "test" {
+  vdisk = "${hedvig_vdisk.test.name}"
+  controller = "iscsi.example.com"
+}
+
+resource "hedvig_access" "test" {
+  vdisk = "${hedvig_vdisk.test.name}"
+  host = "${hedvig_lun.test.controller}"
+  address = "192.168.0.10"
+  type = "ip"
+}
+`),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("hedvig_access.test", "id", "v2/access/unit-access-vdisk/iscsi.example.com/192.168.0.10"),
+					func(*terraform.State) error {
+						vdisk := s.VDisk("unit-access-vdisk")
+						if vdisk == nil || len(vdisk.ACL["iscsi.example.com"]) != 1 {
+							return fmt.Errorf("ACL not persisted on the cluster: %#v", vdisk)
+						}
+						return nil
+					},
+				),
+			},
+		},
+	})
+}
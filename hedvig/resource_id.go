@@ -0,0 +1,103 @@
+package hedvig
+
+import (
+	"fmt"
+	"log"
+	"net/url"
+	"strings"
+
+	"github.com/hashicorp/terraform/helper/schema"
+)
+
+// Resource IDs start with the version of their format and the kind of
+// resource, followed by the values identifying the resource, e.g.
+//
+//	v2/lun/<vdisk>/<controller>
+//
+// Each value is escaped, so that it may contain any character. IDs of
+// earlier versions joined the values with "$" without escaping them; state
+// using them is upgraded by idStateUpgrader.
+const idVersion = "v2"
+
+// buildID returns the ID of a resource of the given kind.
+func buildID(kind string, values ...string) string {
+	fields := []string{idVersion, kind}
+	for _, value := range values {
+		fields = append(fields, url.PathEscape(value))
+	}
+	return strings.Join(fields, "/")
+}
+
+// parseID returns the values of an ID built by buildID for a resource of
+// the given kind. The values are described by names, which are used in
+// errors.
+func parseID(id, kind string, names ...string) ([]string, error) {
+	format := idVersion + "/" + kind + "/<" + strings.Join(names, ">/<") + ">"
+
+	fields := strings.Split(id, "/")
+	if len(fields) != len(names)+2 || fields[0] != idVersion || fields[1] != kind {
+		return nil, fmt.Errorf("Invalid ID %q: expected %s", id, format)
+	}
+
+	values := make([]string, len(names))
+	for i, field := range fields[2:] {
+		value, err := url.PathUnescape(field)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid ID %q: %s: %s", id, names[i], err)
+		}
+		if value == "" {
+			return nil, fmt.Errorf("Invalid ID %q: %s is empty", id, names[i])
+		}
+		values[i] = value
+	}
+	return values, nil
+}
+
+// parseLegacyID returns the n values of a "$" separated ID from before
+// version 2, or false if id is not one.
+func parseLegacyID(id, kind string, n int) ([]string, bool) {
+	fields := strings.Split(id, "$")
+	if len(fields) != n+1 || fields[0] != kind {
+		return nil, false
+	}
+	return fields[1:], true
+}
+
+// idStateUpgrader returns a StateUpgrader from version which rebuilds the ID
+// of a resource of the given kind from the named attributes and leaves the
+// rest of the state as it is. Old state is decoded with the current schema of
+// r, so its attributes must still have the types they had in that version.
+func idStateUpgrader(r *schema.Resource, version int, kind string, attributes ...string) schema.StateUpgrader {
+	return schema.StateUpgrader{
+		Type: r.CoreConfigSchema().ImpliedType(),
+		Upgrade: func(rawState map[string]interface{}, meta interface{}) (map[string]interface{}, error) {
+			values := []string{}
+			for _, k := range attributes {
+				value, _ := rawState[k].(string)
+				if value == "" {
+					return nil, fmt.Errorf("Error upgrading ID %v: %s is not set", rawState["id"], k)
+				}
+				values = append(values, value)
+			}
+
+			id := buildID(kind, values...)
+			log.Printf("[DEBUG] Upgraded ID %v to %s", rawState["id"], id)
+			rawState["id"] = id
+			return rawState, nil
+		},
+		Version: version,
+	}
+}
+
+// importID returns an importer accepting IDs built by buildID for a resource
+// of the given kind.
+func importID(kind string, names ...string) *schema.ResourceImporter {
+	return &schema.ResourceImporter{
+		State: func(d *schema.ResourceData, meta interface{}) ([]*schema.ResourceData, error) {
+			if _, err := parseID(d.Id(), kind, names...); err != nil {
+				return nil, err
+			}
+			return []*schema.ResourceData{d}, nil
+		},
+	}
+}
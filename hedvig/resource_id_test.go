@@ -0,0 +1,108 @@
+package hedvig
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestUnitResourceID_roundTrip(t *testing.T) {
+	cases := []struct {
+		kind   string
+		values []string
+		id     string
+	}{
+		{"vdisk", []string{"data", "BLOCK"}, "v2/vdisk/data/BLOCK"},
+		{"vdisk", []string{"price$list", "NFS"}, "v2/vdisk/price$list/NFS"},
+		{"lun", []string{"data", "iqn.1994-05.com.redhat:node/1"}, "v2/lun/data/iqn.1994-05.com.redhat:node%2F1"},
+		{"access", []string{"data", "host one", "fe80::1%eth0"}, "v2/access/data/host%20one/fe80::1%25eth0"},
+		{"snapshot_policy", []string{"nightly"}, "v2/snapshot_policy/nightly"},
+	}
+
+	for _, tc := range cases {
+		id := buildID(tc.kind, tc.values...)
+		if id != tc.id {
+			t.Errorf("expected ID %q, got %q", tc.id, id)
+		}
+
+		names := make([]string, len(tc.values))
+		for i := range names {
+			names[i] = "value"
+		}
+		values, err := parseID(id, tc.kind, names...)
+		if err != nil {
+			t.Errorf("unexpected error parsing %q: %s", id, err)
+			continue
+		}
+		if !reflect.DeepEqual(values, tc.values) {
+			t.Errorf("expected %q to parse as %v, got %v", id, tc.values, values)
+		}
+	}
+}
+
+func TestUnitResourceID_parseErrors(t *testing.T) {
+	cases := []struct {
+		id  string
+		err string
+	}{
+		{"lun$data$iscsi.example.com", `Invalid ID "lun$data$iscsi.example.com": expected v2/lun/<vdisk>/<controller>`},
+		{"v2/mount/data/nfs.example.com", `expected v2/lun/<vdisk>/<controller>`},
+		{"v2/lun/data", `expected v2/lun/<vdisk>/<controller>`},
+		{"v2/lun/data/iscsi/example", `expected v2/lun/<vdisk>/<controller>`},
+		{"v2/lun//iscsi.example.com", `vdisk is empty`},
+		{"v2/lun/data/%zz", `controller: invalid URL escape "%zz"`},
+	}
+
+	for _, tc := range cases {
+		_, err := parseID(tc.id, "lun", "vdisk", "controller")
+		if err == nil || !strings.Contains(err.Error(), tc.err) {
+			t.Errorf("expected parsing %q to fail with %q, got %v", tc.id, tc.err, err)
+		}
+	}
+}
+
+func TestUnitResourceID_stateUpgrade(t *testing.T) {
+	cases := []struct {
+		upgrader func() (map[string]interface{}, error)
+		id       string
+	}{
+		{
+			func() (map[string]interface{}, error) {
+				return resourceVdisk().StateUpgraders[1].Upgrade(map[string]interface{}{
+					"id":   "vdisk$price$list$BLOCK",
+					"name": "price$list",
+					"type": "BLOCK",
+				}, nil)
+			},
+			"v2/vdisk/price$list/BLOCK",
+		},
+		{
+			func() (map[string]interface{}, error) {
+				return resourceAccess().StateUpgraders[0].Upgrade(map[string]interface{}{
+					"id":      "access$data$host$10.0.0.1",
+					"vdisk":   "data",
+					"host":    "host",
+					"address": "10.0.0.1",
+					"type":    "read-write",
+				}, nil)
+			},
+			"v2/access/data/host/10.0.0.1",
+		},
+	}
+
+	for _, tc := range cases {
+		state, err := tc.upgrader()
+		if err != nil {
+			t.Errorf("unexpected error: %s", err)
+			continue
+		}
+		if state["id"] != tc.id {
+			t.Errorf("expected ID %q, got %v", tc.id, state["id"])
+		}
+	}
+
+	_, err := resourceLun().StateUpgraders[0].Upgrade(map[string]interface{}{"id": "lun$data$", "vdisk": "data"}, nil)
+	if err == nil || !strings.Contains(err.Error(), "controller is not set") {
+		t.Fatalf("expected an error for a missing controller, got %v", err)
+	}
+}
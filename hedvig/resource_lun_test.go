@@ -4,6 +4,7 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"regexp"
 	"testing"
 
 	"github.com/hashicorp/terraform/helper/resource"
@@ -74,3 +75,70 @@ func testAccCheckHedvigLunDestroy(n string) resource.TestCheckFunc {
 		return nil
 	}
 }
+
+func TestUnitHedvigLun_basic(t *testing.T) {
+	s := testUnitServer()
+	defer s.Close()
+
+	resource.UnitTest(t, resource.TestCase{
+		Providers: testAccProviders,
+		CheckDestroy: func(*terraform.State) error {
+			if vdisk := s.VDisk("unit-lun-vdisk"); vdisk != nil && len(vdisk.Luns) > 0 {
+				return fmt.Errorf("LUNs still mapped: %v", vdisk.Luns)
+			}
+			return nil
+		},
+		Steps: []resource.TestStep{
+			{
+				Config: testUnitConfig(s, `
+resource "hedvig_vdisk" "test" {
+  name = "unit-lun-vdisk"
+  size = 9
+  type = "BLOCK"
+}
+
+resource "hedvig_lun" "test" {
+  vdisk = "${hedvig_vdisk.test.name}"
+  controller = "iscsi.example.com"
+}
+`),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("hedvig_lun.test", "id", "v2/lun/unit-lun-vdisk/iscsi.example.com"),
+					func(*terraform.State) error {
+						vdisk := s.VDisk("unit-lun-vdisk")
+						if vdisk == nil || len(vdisk.Luns) != 1 || vdisk.Luns[0] != "iscsi.example.com" {
+							return fmt.Errorf("LUN not mapped on the cluster: %#v", vdisk)
+						}
+						return nil
+					},
+				),
+			},
+		},
+	})
+}
+
+func TestUnitHedvigLun_unknownController(t *testing.T) {
+	s := testUnitServer()
+	defer s.Close()
+
+	resource.UnitTest(t, resource.TestCase{
+		Providers: testAccProviders,
+		Steps: []resource.TestStep{
+			{
+				Config: testUnitConfig(s, `
+resource "hedvig_vdisk" "test" {
+  name = "unit-lun-vdisk"
+  size = 9
+  type = "BLOCK"
+}
+
+resource "hedvig_lun" "test" {
+  vdisk = "${hedvig_vdisk.test.name}"
+  controller = "missing.example.com"
+}
+`),
+				ExpectError: regexp.MustCompile("Unknown controller missing.example.com"),
+			},
+		},
+	})
+}
@@ -4,6 +4,7 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"regexp"
 	"testing"
 
 	"github.com/hashicorp/terraform/helper/resource"
@@ -35,6 +36,8 @@ resource "hedvig_vdisk" "test-mount-vdisk" {
   name = "%s"
   size = 11
   type = "NFS"
+  blocksize = "512"
+  clusteredfilesystem = true
 }
 
 resource "hedvig_mount" "test-mount" {
@@ -73,3 +76,65 @@ func testAccCheckHedvigMountDestroy(n string) resource.TestCheckFunc {
 		return nil
 	}
 }
+
+func TestUnitHedvigMount_basic(t *testing.T) {
+	s := testUnitServer()
+	defer s.Close()
+
+	resource.UnitTest(t, resource.TestCase{
+		Providers: testAccProviders,
+		CheckDestroy: func(*terraform.State) error {
+			if vdisk := s.VDisk("unit-mount-vdisk"); vdisk != nil && len(vdisk.Exports) > 0 {
+				return fmt.Errorf("vdisk still mounted: %v", vdisk.Exports)
+			}
+			return nil
+		},
+		Steps: []resource.TestStep{
+			{
+				Config: testUnitConfig(s, testUnitHedvigMountConfig("nfs.example.com")),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("hedvig_mount.test", "id", "v2/mount/unit-mount-vdisk/nfs.example.com"),
+					func(*terraform.State) error {
+						vdisk := s.VDisk("unit-mount-vdisk")
+						if vdisk == nil || len(vdisk.Exports) != 1 {
+							return fmt.Errorf("vdisk not mounted on the cluster: %#v", vdisk)
+						}
+						return nil
+					},
+				),
+			},
+		},
+	})
+}
+
+func TestUnitHedvigMount_notNFS(t *testing.T) {
+	s := testUnitServer()
+	defer s.Close()
+
+	resource.UnitTest(t, resource.TestCase{
+		Providers: testAccProviders,
+		Steps: []resource.TestStep{
+			{
+				Config:      testUnitConfig(s, testUnitHedvigMountConfig("iscsi.example.com")),
+				ExpectError: regexp.MustCompile("Given controller not NFS -- try nfs.example.com"),
+			},
+		},
+	})
+}
+
+func testUnitHedvigMountConfig(controller string) string {
+	return fmt.Sprintf(`
+resource "hedvig_vdisk" "test" {
+  name = "unit-mount-vdisk"
+  size = 11
+  type = "NFS"
+  blocksize = "512"
+  clusteredfilesystem = true
+}
+
+resource "hedvig_mount" "test" {
+  vdisk = "${hedvig_vdisk.test.name}"
+  controller = "%s"
+}
+`, controller)
+}
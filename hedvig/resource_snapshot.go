@@ -0,0 +1,119 @@
+package hedvig
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/hashicorp/terraform/helper/schema"
+	"github.com/terraform-providers/terraform-provider-hedvig/hedvig/client"
+)
+
+func resourceSnapshot() *schema.Resource {
+	return &schema.Resource{
+		Create:   resourceSnapshotCreate,
+		Read:     resourceSnapshotRead,
+		Delete:   resourceSnapshotDelete,
+		Importer: importID("snapshot", "vdisk", "name"),
+
+		Timeouts: &schema.ResourceTimeout{
+			Create: schema.DefaultTimeout(5 * time.Minute),
+			Read:   schema.DefaultTimeout(5 * time.Minute),
+			Delete: schema.DefaultTimeout(5 * time.Minute),
+		},
+
+		Schema: map[string]*schema.Schema{
+			"vdisk": {
+				Type:     schema.TypeString,
+				Required: true,
+				ForceNew: true,
+			},
+			"name": {
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+			"created_at": {
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+			"size_bytes": {
+				Type:     schema.TypeInt,
+				Computed: true,
+			},
+		},
+	}
+}
+
+func resourceSnapshotCreate(d *schema.ResourceData, meta interface{}) error {
+	ctx, cancel := context.WithTimeout(context.Background(), d.Timeout(schema.TimeoutCreate))
+	defer cancel()
+
+	vdisk := d.Get("vdisk").(string)
+	resp, err := meta.(*HedvigClient).CreateSnapshot(ctx, &client.CreateSnapshot{VirtualDisk: vdisk})
+	if err != nil {
+		return fmt.Errorf("Error creating snapshot of vdisk %q: %s", vdisk, err)
+	}
+
+	d.SetId(buildID("snapshot", vdisk, resp.Result.Name))
+
+	return resourceSnapshotRead(d, meta)
+}
+
+func resourceSnapshotRead(d *schema.ResourceData, meta interface{}) error {
+	ctx, cancel := context.WithTimeout(context.Background(), d.Timeout(schema.TimeoutRead))
+	defer cancel()
+
+	id, err := parseID(d.Id(), "snapshot", "vdisk", "name")
+	if err != nil {
+		return err
+	}
+
+	snapshot, err := meta.(*HedvigClient).SnapshotDetails(ctx, id[0], id[1])
+	if client.IsNotFound(err) {
+		d.SetId("")
+		log.Printf("Snapshot %s not found, clearing from state", id[1])
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("Error reading snapshot %q: %s", id[1], err)
+	}
+
+	d.Set("vdisk", id[0])
+	return flattenSnapshot(d, snapshot)
+}
+
+func resourceSnapshotDelete(d *schema.ResourceData, meta interface{}) error {
+	ctx, cancel := context.WithTimeout(context.Background(), d.Timeout(schema.TimeoutDelete))
+	defer cancel()
+
+	id, err := parseID(d.Id(), "snapshot", "vdisk", "name")
+	if err != nil {
+		return err
+	}
+
+	_, err = meta.(*HedvigClient).DeleteSnapshot(ctx, &client.DeleteSnapshot{
+		VirtualDisk: id[0],
+		Snapshots:   []string{id[1]},
+	})
+	if client.IsNotFound(err) {
+		log.Printf("Snapshot %s already deleted", id[1])
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("Error deleting snapshot %q: %s", id[1], err)
+	}
+	return nil
+}
+
+func flattenSnapshot(d *schema.ResourceData, snapshot *client.Snapshot) error {
+	sizeBytes, err := intBytes(snapshot.Size.Bytes())
+	if err != nil {
+		return fmt.Errorf("Error reading the size of snapshot %q: %s", snapshot.Name, err)
+	}
+
+	d.Set("name", snapshot.Name)
+	d.Set("created_at", snapshot.Created().Format(time.RFC3339))
+	d.Set("size_bytes", sizeBytes)
+	return nil
+}
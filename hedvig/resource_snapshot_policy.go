@@ -0,0 +1,232 @@
+package hedvig
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/terraform/helper/schema"
+	"github.com/hashicorp/terraform/helper/validation"
+	"github.com/terraform-providers/terraform-provider-hedvig/hedvig/client"
+)
+
+func resourceSnapshotPolicy() *schema.Resource {
+	return &schema.Resource{
+		Create:   resourceSnapshotPolicyCreate,
+		Read:     resourceSnapshotPolicyRead,
+		Update:   resourceSnapshotPolicyUpdate,
+		Delete:   resourceSnapshotPolicyDelete,
+		Importer: importID("snapshot_policy", "name"),
+
+		CustomizeDiff: resourceSnapshotPolicyCustomizeDiff,
+
+		Timeouts: &schema.ResourceTimeout{
+			Create: schema.DefaultTimeout(5 * time.Minute),
+			Read:   schema.DefaultTimeout(5 * time.Minute),
+			Update: schema.DefaultTimeout(5 * time.Minute),
+			Delete: schema.DefaultTimeout(5 * time.Minute),
+		},
+
+		Schema: map[string]*schema.Schema{
+			"name": {
+				Type:     schema.TypeString,
+				Required: true,
+				ForceNew: true,
+			},
+			"interval": {
+				Type:             schema.TypeString,
+				Optional:         true,
+				ConflictsWith:    []string{"cron"},
+				ValidateFunc:     validateSnapshotInterval,
+				DiffSuppressFunc: suppressEquivalentInterval,
+			},
+			"cron": {
+				Type:          schema.TypeString,
+				Optional:      true,
+				ConflictsWith: []string{"interval"},
+				ValidateFunc:  validateCron,
+			},
+			"retention": {
+				Type:         schema.TypeInt,
+				Required:     true,
+				ValidateFunc: validation.IntAtLeast(1),
+			},
+			"vdisks": {
+				Type:     schema.TypeSet,
+				Required: true,
+				MinItems: 1,
+				Elem:     &schema.Schema{Type: schema.TypeString},
+			},
+		},
+	}
+}
+
+func resourceSnapshotPolicyCreate(d *schema.ResourceData, meta interface{}) error {
+	ctx, cancel := context.WithTimeout(context.Background(), d.Timeout(schema.TimeoutCreate))
+	defer cancel()
+
+	name := d.Get("name").(string)
+	_, err := meta.(*HedvigClient).AddSnapshotPolicy(ctx, &client.AddSnapshotPolicy{
+		SnapshotPolicy: expandSnapshotPolicy(d),
+	})
+	if client.IsAlreadyExists(err) {
+		return fmt.Errorf("A snapshot policy named %q already exists on the cluster", name)
+	}
+	if err != nil {
+		return fmt.Errorf("Error creating snapshot policy %q: %s", name, err)
+	}
+
+	d.SetId(buildID("snapshot_policy", name))
+
+	return resourceSnapshotPolicyRead(d, meta)
+}
+
+func resourceSnapshotPolicyRead(d *schema.ResourceData, meta interface{}) error {
+	ctx, cancel := context.WithTimeout(context.Background(), d.Timeout(schema.TimeoutRead))
+	defer cancel()
+
+	id, err := parseID(d.Id(), "snapshot_policy", "name")
+	if err != nil {
+		return err
+	}
+
+	readResp, err := meta.(*HedvigClient).SnapshotPolicyDetails(ctx, id[0])
+	if client.IsNotFound(err) {
+		d.SetId("")
+		log.Printf("Snapshot policy %s not found, clearing from state", id[0])
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("Error reading snapshot policy %q: %s", id[0], err)
+	}
+
+	policy := readResp.Result
+	d.Set("name", policy.Name)
+	d.Set("retention", policy.Retention)
+	d.Set("vdisks", policy.VirtualDisks)
+	d.Set("cron", policy.Cron)
+
+	// Keep the configured spelling of an unchanged interval, e.g. "60m"
+	// rather than "1h".
+	interval := ""
+	if policy.IntervalMinutes > 0 {
+		interval = formatInterval(policy.IntervalMinutes)
+		if suppressEquivalentInterval("interval", d.Get("interval").(string), interval, d) {
+			interval = d.Get("interval").(string)
+		}
+	}
+	d.Set("interval", interval)
+
+	return nil
+}
+
+func resourceSnapshotPolicyUpdate(d *schema.ResourceData, meta interface{}) error {
+	ctx, cancel := context.WithTimeout(context.Background(), d.Timeout(schema.TimeoutUpdate))
+	defer cancel()
+
+	_, err := meta.(*HedvigClient).UpdateSnapshotPolicy(ctx, &client.UpdateSnapshotPolicy{
+		SnapshotPolicy: expandSnapshotPolicy(d),
+	})
+	if err != nil {
+		return fmt.Errorf("Error updating snapshot policy %q: %s", d.Get("name").(string), err)
+	}
+
+	return resourceSnapshotPolicyRead(d, meta)
+}
+
+func resourceSnapshotPolicyDelete(d *schema.ResourceData, meta interface{}) error {
+	ctx, cancel := context.WithTimeout(context.Background(), d.Timeout(schema.TimeoutDelete))
+	defer cancel()
+
+	id, err := parseID(d.Id(), "snapshot_policy", "name")
+	if err != nil {
+		return err
+	}
+
+	_, err = meta.(*HedvigClient).DeleteSnapshotPolicy(ctx, &client.DeleteSnapshotPolicy{Name: id[0]})
+	if client.IsNotFound(err) {
+		log.Printf("Snapshot policy %s already deleted", id[0])
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("Error deleting snapshot policy %q: %s", id[0], err)
+	}
+	return nil
+}
+
+func resourceSnapshotPolicyCustomizeDiff(d *schema.ResourceDiff, meta interface{}) error {
+	if !d.NewValueKnown("interval") || !d.NewValueKnown("cron") {
+		return nil
+	}
+	if d.Get("interval").(string) == "" && d.Get("cron").(string) == "" {
+		return errors.New("One of interval or cron must be set")
+	}
+	return nil
+}
+
+func expandSnapshotPolicy(d *schema.ResourceData) client.SnapshotPolicy {
+	policy := client.SnapshotPolicy{
+		Name:      d.Get("name").(string),
+		Cron:      d.Get("cron").(string),
+		Retention: d.Get("retention").(int),
+	}
+	if interval, err := time.ParseDuration(d.Get("interval").(string)); err == nil {
+		policy.IntervalMinutes = int(interval / time.Minute)
+	}
+	for _, vdisk := range d.Get("vdisks").(*schema.Set).List() {
+		policy.VirtualDisks = append(policy.VirtualDisks, vdisk.(string))
+	}
+	return policy
+}
+
+// formatInterval formats a number of minutes as a duration without zero
+// units, e.g. "1h" or "1h30m".
+func formatInterval(minutes int) string {
+	interval := strings.TrimSuffix((time.Duration(minutes) * time.Minute).String(), "0s")
+	if strings.HasSuffix(interval, "h0m") {
+		interval = strings.TrimSuffix(interval, "0m")
+	}
+	return interval
+}
+
+// suppressEquivalentInterval hides differences in the spelling of the same
+// interval, such as "60m" and "1h".
+func suppressEquivalentInterval(k, old, new string, d *schema.ResourceData) bool {
+	o, err := time.ParseDuration(old)
+	if err != nil {
+		return false
+	}
+	n, err := time.ParseDuration(new)
+	return err == nil && o == n
+}
+
+func validateSnapshotInterval(v interface{}, k string) (ws []string, es []error) {
+	d, err := time.ParseDuration(v.(string))
+	if err != nil {
+		es = append(es, fmt.Errorf("%q: %s", k, err))
+		return
+	}
+	if d < time.Minute || d%time.Minute != 0 {
+		es = append(es, fmt.Errorf("%q must be a whole number of minutes, got %s", k, d))
+	}
+	return
+}
+
+// validateCron accepts schedules of five space separated fields: minute,
+// hour, day of month, month and day of week.
+func validateCron(v interface{}, k string) (ws []string, es []error) {
+	fields := strings.Fields(v.(string))
+	if len(fields) != 5 {
+		es = append(es, fmt.Errorf("%q must have 5 fields (minute hour day-of-month month day-of-week), got %d", k, len(fields)))
+		return
+	}
+	for _, field := range fields {
+		if strings.Trim(field, "0123456789*/,-") != "" {
+			es = append(es, fmt.Errorf("%q: invalid field %q", k, field))
+		}
+	}
+	return
+}
@@ -0,0 +1,179 @@
+package hedvig
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"testing"
+
+	"github.com/hashicorp/terraform/helper/resource"
+	"github.com/hashicorp/terraform/terraform"
+)
+
+func TestAccHedvigSnapshotPolicy(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		PreCheck:  func() { testAccPreCheck(t) },
+		Providers: testAccProviders,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccHedvigSnapshotPolicyConfig,
+				Check:  resource.TestCheckResourceAttr("hedvig_snapshot_policy.test-policy", "retention", "7"),
+			},
+			{
+				ResourceName:      "hedvig_snapshot_policy.test-policy",
+				ImportState:       true,
+				ImportStateVerify: true,
+			},
+		},
+	})
+}
+
+var testAccHedvigSnapshotPolicyConfig = fmt.Sprintf(`
+provider "hedvig" {
+  node = "%s"
+  username = "%s"
+  password = "%s"
+}
+
+resource "hedvig_vdisk" "test-policy-vdisk" {
+  name = "%s"
+  size = 9
+  type = "BLOCK"
+}
+
+resource "hedvig_snapshot_policy" "test-policy" {
+  name = "${hedvig_vdisk.test-policy-vdisk.name}-daily"
+  cron = "0 2 * * *"
+  retention = 7
+  vdisks = ["${hedvig_vdisk.test-policy-vdisk.name}"]
+}
+`, os.Getenv("HV_TESTNODE"), os.Getenv("HV_TESTUSER"), os.Getenv("HV_TESTPASS"),
+	genRandomVdiskName())
+
+func TestUnitHedvigSnapshotPolicy_basic(t *testing.T) {
+	s := testUnitServer()
+	defer s.Close()
+
+	resource.UnitTest(t, resource.TestCase{
+		Providers: testAccProviders,
+		CheckDestroy: func(*terraform.State) error {
+			s.Lock()
+			defer s.Unlock()
+			for name := range s.SnapshotPolicies {
+				return fmt.Errorf("snapshot policy %s still exists", name)
+			}
+			return nil
+		},
+		Steps: []resource.TestStep{
+			{
+				Config: testUnitConfig(s, testUnitHedvigSnapshotPolicyConfig(`interval = "1h"`, 24)),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("hedvig_snapshot_policy.test", "id", "v2/snapshot_policy/unit-policy"),
+					resource.TestCheckResourceAttr("hedvig_snapshot_policy.test", "interval", "1h"),
+					resource.TestCheckResourceAttr("hedvig_snapshot_policy.test", "vdisks.#", "2"),
+					func(*terraform.State) error {
+						s.Lock()
+						defer s.Unlock()
+						if p := s.SnapshotPolicies["unit-policy"]; p == nil || p.IntervalMinutes != 60 || p.Retention != 24 {
+							return fmt.Errorf("unexpected snapshot policy on the cluster: %#v", p)
+						}
+						return nil
+					},
+				),
+			},
+			{
+				Config:            testUnitConfig(s, testUnitHedvigSnapshotPolicyConfig(`interval = "1h"`, 24)),
+				ResourceName:      "hedvig_snapshot_policy.test",
+				ImportState:       true,
+				ImportStateVerify: true,
+			},
+			{
+				Config:   testUnitConfig(s, testUnitHedvigSnapshotPolicyConfig(`interval = "60m"`, 24)),
+				PlanOnly: true,
+			},
+			{
+				Config: testUnitConfig(s, testUnitHedvigSnapshotPolicyConfig(`cron = "0 2 * * *"`, 7)),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("hedvig_snapshot_policy.test", "cron", "0 2 * * *"),
+					resource.TestCheckResourceAttr("hedvig_snapshot_policy.test", "interval", ""),
+					resource.TestCheckResourceAttr("hedvig_snapshot_policy.test", "retention", "7"),
+					func(*terraform.State) error {
+						// Someone changes the schedule in the UI.
+						s.Lock()
+						defer s.Unlock()
+						s.SnapshotPolicies["unit-policy"].Cron = "0 3 * * *"
+						return nil
+					},
+				),
+				ExpectNonEmptyPlan: true,
+			},
+			{
+				Config: testUnitConfig(s, testUnitHedvigSnapshotPolicyConfig(`cron = "0 2 * * *"`, 7)),
+				Check: func(*terraform.State) error {
+					s.Lock()
+					defer s.Unlock()
+					if cron := s.SnapshotPolicies["unit-policy"].Cron; cron != "0 2 * * *" {
+						return fmt.Errorf("drift not corrected, cron is %q", cron)
+					}
+					return nil
+				},
+			},
+		},
+	})
+
+	if n := s.Requests["AddSnapshotPolicy"]; n != 1 {
+		t.Fatalf("expected the snapshot policy to be updated in place, got %d creates", n)
+	}
+}
+
+func TestUnitHedvigSnapshotPolicy_schedule(t *testing.T) {
+	s := testUnitServer()
+	defer s.Close()
+
+	cases := []struct {
+		schedule string
+		expected string
+	}{
+		{``, "One of interval or cron must be set"},
+		{"interval = \"1h\"\n  cron = \"0 2 * * *\"", "conflicts with"},
+		{`interval = "90s"`, "must be a whole number of minutes"},
+		{`cron = "0 2 * *"`, "must have 5 fields"},
+		{`cron = "0 2 * * mon"`, "invalid field \"mon\""},
+	}
+
+	for _, tc := range cases {
+		resource.UnitTest(t, resource.TestCase{
+			Providers: testAccProviders,
+			Steps: []resource.TestStep{
+				{
+					Config:      testUnitConfig(s, testUnitHedvigSnapshotPolicyConfig(tc.schedule, 7)),
+					PlanOnly:    true,
+					ExpectError: regexp.MustCompile(regexp.QuoteMeta(tc.expected)),
+				},
+			},
+		})
+	}
+}
+
+func testUnitHedvigSnapshotPolicyConfig(schedule string, retention int) string {
+	return fmt.Sprintf(`
+resource "hedvig_vdisk" "first" {
+  name = "unit-policy-first"
+  size = 9
+  type = "BLOCK"
+}
+
+resource "hedvig_vdisk" "second" {
+  name = "unit-policy-second"
+  size = 9
+  type = "BLOCK"
+}
+
+resource "hedvig_snapshot_policy" "test" {
+  name = "unit-policy"
+  %s
+  retention = %d
+  vdisks = ["${hedvig_vdisk.first.name}", "${hedvig_vdisk.second.name}"]
+}
+`, schedule, retention)
+}
@@ -0,0 +1,105 @@
+package hedvig
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"testing"
+
+	"github.com/hashicorp/terraform/helper/resource"
+	"github.com/hashicorp/terraform/terraform"
+)
+
+func TestAccHedvigSnapshot(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		PreCheck:  func() { testAccPreCheck(t) },
+		Providers: testAccProviders,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccHedvigSnapshotConfig,
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttrSet("hedvig_snapshot.test-snapshot", "name"),
+					resource.TestCheckResourceAttrSet("hedvig_snapshot.test-snapshot", "created_at"),
+				),
+			},
+			{
+				ResourceName:      "hedvig_snapshot.test-snapshot",
+				ImportState:       true,
+				ImportStateVerify: true,
+			},
+		},
+	})
+}
+
+var testAccHedvigSnapshotConfig = fmt.Sprintf(`
+provider "hedvig" {
+  node = "%s"
+  username = "%s"
+  password = "%s"
+}
+
+resource "hedvig_vdisk" "test-snapshot-vdisk" {
+  name = "%s"
+  size = 9
+  type = "BLOCK"
+}
+
+resource "hedvig_snapshot" "test-snapshot" {
+  vdisk = "${hedvig_vdisk.test-snapshot-vdisk.name}"
+}
+`, os.Getenv("HV_TESTNODE"), os.Getenv("HV_TESTUSER"), os.Getenv("HV_TESTPASS"),
+	genRandomVdiskName())
+
+func TestUnitHedvigSnapshot_basic(t *testing.T) {
+	s := testUnitServer()
+	defer s.Close()
+
+	resource.UnitTest(t, resource.TestCase{
+		Providers: testAccProviders,
+		CheckDestroy: func(*terraform.State) error {
+			if vdisk := s.VDisk("unit-snapshot-vdisk"); vdisk != nil && len(vdisk.Snapshots) > 0 {
+				return fmt.Errorf("snapshots still exist: %v", vdisk.Snapshots)
+			}
+			return nil
+		},
+		Steps: []resource.TestStep{
+			{
+				Config: testUnitConfig(s, testUnitHedvigSnapshotConfig),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("hedvig_snapshot.test", "id", "v2/snapshot/unit-snapshot-vdisk/unit-snapshot-vdisk-snap-1"),
+					resource.TestCheckResourceAttr("hedvig_snapshot.test", "name", "unit-snapshot-vdisk-snap-1"),
+					resource.TestCheckResourceAttr("hedvig_snapshot.test", "size_bytes", "9663676416"),
+					resource.TestMatchResourceAttr("hedvig_snapshot.test", "created_at", regexp.MustCompile(`^\d{4}-\d{2}-\d{2}T`)),
+				),
+			},
+			{
+				Config:            testUnitConfig(s, testUnitHedvigSnapshotConfig),
+				ResourceName:      "hedvig_snapshot.test",
+				ImportState:       true,
+				ImportStateVerify: true,
+			},
+			{
+				Config: testUnitConfig(s, testUnitHedvigSnapshotConfig),
+				Check: func(*terraform.State) error {
+					s.Lock()
+					defer s.Unlock()
+					s.VDisks["unit-snapshot-vdisk"].Snapshots = nil
+					return nil
+				},
+				ExpectNonEmptyPlan: true,
+			},
+		},
+	})
+}
+
+const testUnitHedvigSnapshotConfig = `
+resource "hedvig_vdisk" "test" {
+  name = "unit-snapshot-vdisk"
+  size = 9
+  type = "BLOCK"
+}
+
+resource "hedvig_snapshot" "test" {
+  vdisk = "${hedvig_vdisk.test.name}"
+}
+`
@@ -1,83 +1,49 @@
 package hedvig
 
 import (
-	"encoding/json"
+	"context"
 	"errors"
 	"fmt"
-	"io/ioutil"
 	"log"
-	"net/http"
-	"net/url"
+	"regexp"
+	"strconv"
 	"strings"
+	"time"
 
+	"github.com/hashicorp/terraform/helper/resource"
 	"github.com/hashicorp/terraform/helper/schema"
 	"github.com/hashicorp/terraform/helper/validation"
+	"github.com/terraform-providers/terraform-provider-hedvig/hedvig/client"
 )
 
-type createDiskResponse struct {
-	Result []struct {
-		Name    string `json:"name"`
-		Message string `json:"message"`
-		Status  string `json:"status"`
-	} `json:"result"`
-	RequestID           string `json:"requestId"`
-	Type                string `json:"type"`
-	Status              string `json:"status"`
-	Residence           string `json:"residence"`
-	Message             string `json:"message"`
-	ReplicationFactor   string `json:"replicationFactor"`
-	Deduplication       string `json:"deduplication"`
-	Compressed          string `json:"compressed"`
-	BlockSize           string `json:"blockSize"`
-	ClusteredFileSystem string `json:"clusteredFileSystem"`
-	CacheEnabled        string `json:"cacheEnabled"`
-	Scsi3pr             string `json:"scsi3pr"`
-	Encryption          string `json:"encryption"`
-	ReplicationPolicy   string `json:"replicationPolicy"`
-	Description         string `json:"description"`
-}
-
-type readDiskResponse struct {
-	Result struct {
-		VDiskName string `json:"vDiskName"`
-		Size      struct {
-			Units string `json:"units"`
-			Value int    `json:"value"`
-		} `json:"size"`
-		DiskType string `json:"diskType"`
-	} `json:"result"`
-	Status  string `json:"status"`
-	Message string `json:"message"`
-}
-
-type updateDiskResponse struct {
-	RequestID string `json:"requestId"`
-	Result    []struct {
-		Name   string `json:"name"`
-		Status string `json:"status"`
-	} `json:"result"`
-	Status string `json:"status"`
-	Type   string `json:"type"`
-}
-
-type deleteDiskResponse struct {
-	Result []struct {
-		Name    string `json:"name"`
-		Message string `json:"message"`
-		Status  string `json:"status"`
-	} `json:"result"`
-	RequestID string `json:"requestId"`
-	Type      string `json:"type"`
-	Status    string `json:"status"`
-}
-
 func resourceVdisk() *schema.Resource {
-	return &schema.Resource{
+	r := &schema.Resource{
 		Create: resourceVdiskCreate,
 		Read:   resourceVdiskRead,
 		Update: resourceVdiskUpdate,
 		Delete: resourceVdiskDelete,
 
+		SchemaVersion: 2,
+		StateUpgraders: []schema.StateUpgrader{
+			{
+				Type:    resourceVdiskV0().CoreConfigSchema().ImpliedType(),
+				Upgrade: resourceVdiskStateUpgradeV0,
+				Version: 0,
+			},
+		},
+
+		CustomizeDiff: resourceVdiskCustomizeDiff,
+		Importer: &schema.ResourceImporter{
+			State: resourceVdiskImport,
+		},
+
+		Timeouts: &schema.ResourceTimeout{
+			Create: schema.DefaultTimeout(10 * time.Minute),
+			Read:   schema.DefaultTimeout(5 * time.Minute),
+			Update: schema.DefaultTimeout(60 * time.Minute),
+			Delete: schema.DefaultTimeout(10 * time.Minute),
+		},
+
 		Schema: map[string]*schema.Schema{
 			"name": {
 				Type:     schema.TypeString,
@@ -88,15 +54,56 @@ func resourceVdisk() *schema.Resource {
 				Type:     schema.TypeInt,
 				Required: true,
 			},
-			"residence": {
+			"size_unit": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				Default:      "GB",
+				ValidateFunc: validation.StringInSlice([]string{"GB", "TB"}, true),
+			},
+			"size_bytes": {
+				Type:     schema.TypeInt,
+				Computed: true,
+			},
+			"source_vdisk": {
 				Type:     schema.TypeString,
 				Optional: true,
 				ForceNew: true,
-				Default:  "HDD",
-				ValidateFunc: validation.StringInSlice([]string{
-					"Flash",
-					"HDD",
-				}, true),
+			},
+			"source_snapshot": {
+				Type:     schema.TypeString,
+				Optional: true,
+				ForceNew: true,
+			},
+			"replace_on_migration": {
+				Type:     schema.TypeBool,
+				Optional: true,
+				Default:  false,
+			},
+			"allow_replace_on_shrink": {
+				Type:     schema.TypeBool,
+				Optional: true,
+				Default:  false,
+			},
+			"adopt_existing": {
+				Type:     schema.TypeBool,
+				Optional: true,
+				Default:  false,
+			},
+			"deletion_protection": {
+				Type:     schema.TypeBool,
+				Optional: true,
+				Default:  false,
+			},
+			"force_detach": {
+				Type:     schema.TypeBool,
+				Optional: true,
+				Default:  false,
+			},
+			"residence": {
+				Type:             schema.TypeString,
+				Optional:         true,
+				DiffSuppressFunc: suppressVdiskUnset,
+				ValidateFunc:     validation.StringInSlice(vdiskResidences, true),
 			},
 			"type": {
 				Type:     schema.TypeString,
@@ -108,33 +115,28 @@ func resourceVdisk() *schema.Resource {
 				}, true),
 			},
 			"replicationfactor": {
-				Type:         schema.TypeInt,
-				Optional:     true,
-				Default:      "3",
-				ForceNew:     true,
-				ValidateFunc: validation.IntBetween(1, 6),
+				Type:             schema.TypeInt,
+				Optional:         true,
+				DiffSuppressFunc: suppressVdiskUnset,
+				ValidateFunc:     validation.IntBetween(1, 6),
 			},
 			"deduplication": {
-				Type:     schema.TypeBool,
-				Optional: true,
-				Default:  false,
-				ForceNew: true,
+				Type:             schema.TypeBool,
+				Optional:         true,
+				DiffSuppressFunc: suppressVdiskUnset,
+				ForceNew:         true,
 			},
 			"compressed": {
-				Type:     schema.TypeString,
-				Optional: true,
-				Default:  "false",
-				ForceNew: true,
-				ValidateFunc: validation.StringInSlice([]string{
-					"true",
-					"false",
-				}, true),
+				Type:             schema.TypeBool,
+				Optional:         true,
+				DiffSuppressFunc: suppressVdiskUnset,
+				ForceNew:         true,
 			},
 			"blocksize": {
-				Type:     schema.TypeString,
-				Optional: true,
-				Default:  "4096",
-				ForceNew: true,
+				Type:             schema.TypeString,
+				Optional:         true,
+				DiffSuppressFunc: suppressVdiskUnset,
+				ForceNew:         true,
 				ValidateFunc: validation.StringInSlice([]string{
 					"512",
 					"4096",
@@ -144,343 +146,959 @@ func resourceVdisk() *schema.Resource {
 				}, true),
 			},
 			"clusteredfilesystem": {
-				Type:     schema.TypeString,
-				Optional: true,
-				Default:  "false",
-				ForceNew: true,
-				ValidateFunc: validation.StringInSlice([]string{
-					"true",
-					"false",
-				}, true),
+				Type:             schema.TypeBool,
+				Optional:         true,
+				DiffSuppressFunc: suppressVdiskUnset,
+				ForceNew:         true,
 			},
 			"scsi3pr": {
-				Type:     schema.TypeString,
-				Optional: true,
-				Default:  "false",
-				ForceNew: true,
-				ValidateFunc: validation.StringInSlice([]string{
-					"true",
-					"false",
-				}, true),
+				Type:             schema.TypeBool,
+				Optional:         true,
+				DiffSuppressFunc: suppressVdiskUnset,
+				ForceNew:         true,
 			},
 			"cacheenabled": {
-				Type:     schema.TypeString,
+				Type:     schema.TypeBool,
 				Optional: true,
-				ForceNew: true,
-				Default:  "false",
-				ValidateFunc: validation.StringInSlice([]string{
-					"true",
-					"false",
-				}, true),
+				Default:  false,
 			},
 			"encryption": {
-				Type:     schema.TypeString,
-				Optional: true,
-				ForceNew: true,
-				Default:  "false",
-				ValidateFunc: validation.StringInSlice([]string{
-					"true",
-					"false",
-				}, true),
+				Type:             schema.TypeBool,
+				Optional:         true,
+				DiffSuppressFunc: suppressVdiskUnset,
+				ForceNew:         true,
 			},
 			"description": {
 				Type:     schema.TypeString,
 				Optional: true,
-				ForceNew: true,
 				Default:  "",
 			},
 			"replicationpolicy": {
-				Type:     schema.TypeString,
-				Optional: true,
-				ForceNew: true,
-				Default:  "Agnostic",
-				ValidateFunc: validation.StringInSlice([]string{
-					"Agnostic",
-					"DataCenterAware",
-					"RackAware",
-					//		"RackUnaware",
-				}, true),
+				Type:             schema.TypeString,
+				Optional:         true,
+				DiffSuppressFunc: suppressVdiskUnset,
+				ValidateFunc:     validation.StringInSlice(vdiskReplicationPolicies, true),
 			},
 		},
 	}
+	r.StateUpgraders = append(r.StateUpgraders, idStateUpgrader(r, 1, "vdisk", "name", "type"))
+	return r
 }
 
 func resourceVdiskCreate(d *schema.ResourceData, meta interface{}) error {
-	u := url.URL{}
-	u.Host = meta.(*HedvigClient).Node
-	u.Path = "/rest/"
-	u.Scheme = "http"
+	ctx, cancel := context.WithTimeout(context.Background(), d.Timeout(schema.TimeoutCreate))
+	defer cancel()
+
+	if d.Get("source_vdisk").(string) != "" {
+		if err := resourceVdiskClone(ctx, d, meta.(*HedvigClient)); err != nil {
+			return err
+		}
+		return resourceVdiskRead(d, meta)
+	}
 
-	sessionID, err := GetSessionId(d, meta.(*HedvigClient))
+	blocksize, err := parseBlockSize(vdiskSetting(d, "blocksize").(string))
 	if err != nil {
 		return err
 	}
 
-	compress := "false"
-
-	if (d.Get("deduplication") == "true") && (d.Get("compressed") == "false") {
-		return fmt.Errorf("Deduplication enabled, compression must also be enabled.")
-	} else if d.Get("compressed") == "true" {
-		compress = "true"
+	req := &client.AddVirtualDisk{
+		Name:                d.Get("name").(string),
+		Size:                vdiskSize(d.Get("size").(int), d.Get("size_unit").(string)),
+		DiskType:            d.Get("type").(string),
+		Residence:           vdiskSetting(d, "residence").(string),
+		ReplicationFactor:   vdiskSetting(d, "replicationfactor").(int),
+		Deduplication:       d.Get("deduplication").(bool),
+		Compressed:          d.Get("compressed").(bool),
+		BlockSize:           blocksize,
+		Scsi3pr:             d.Get("scsi3pr").(bool),
+		CacheEnabled:        d.Get("cacheenabled").(bool),
+		ReplicationPolicy:   vdiskSetting(d, "replicationpolicy").(string),
+		ClusteredFileSystem: d.Get("clusteredfilesystem").(bool),
+		Encryption:          d.Get("encryption").(bool),
+		Description:         d.Get("description").(string),
 	}
-
-	if d.Get("deduplication") == "true" && d.Get("type") == "BLOCK" && d.Get("clusteredfilesystem") == "true" {
-		return fmt.Errorf("Deduplication cannot be enabled for a block virtual disk with a clustered file system.")
+	_, err = meta.(*HedvigClient).AddVirtualDisk(ctx, req)
+	if client.IsKMSNotConfigured(err) {
+		return fmt.Errorf("Cannot enable encryption without setting up KMS. Please refer to the Hedvig Encrypt360 Guide for assistance.")
 	}
-
-	if d.Get("blocksize").(string) != "512" && d.Get("type") == "NFS" {
-		return fmt.Errorf("Block size must be 512 on NFS disks")
+	if client.IsAlreadyExists(err) {
+		if !d.Get("adopt_existing").(bool) {
+			return fmt.Errorf("A vdisk named %q already exists on the cluster; import it or set adopt_existing to take it over", d.Get("name").(string))
+		}
+		if err := resourceVdiskAdopt(ctx, meta.(*HedvigClient), req); err != nil {
+			return err
+		}
+	} else if err != nil {
+		return fmt.Errorf("Error creating vdisk %q: %s", d.Get("name").(string), err)
 	}
 
-	//if !(d.Get("blocksize").(string) == "4k" || d.Get("blocksize").(string) == "4096") {
-	//	if d.Get("deduplication") == "true" {
-	//		return fmt.Errorf("Deduplication enabled, block size must be 4k (or 4096)")
-	//	}
-	//}
+	d.SetId(buildID("vdisk", d.Get("name").(string), d.Get("type").(string)))
 
-	blocksize := ""
+	return resourceVdiskRead(d, meta)
+}
 
-	if d.Get("blocksize").(string) == "4k" {
-		blocksize = "4096"
-	} else if d.Get("blocksize").(string) == "64k" {
-		blocksize = "65536"
-	} else {
-		blocksize = d.Get("blocksize").(string)
+// resourceVdiskAdopt takes over an existing disk of the configured name,
+// e.g. one created by an earlier apply that failed before recording it, if
+// its settings match the configuration.
+func resourceVdiskAdopt(ctx context.Context, c *HedvigClient, req *client.AddVirtualDisk) error {
+	readResp, err := c.VirtualDiskDetails(ctx, req.Name)
+	if err != nil {
+		return fmt.Errorf("Error reading existing vdisk %q: %s", req.Name, err)
 	}
 
-	if d.Get("residence").(string) != "HDD" && d.Get("deduplication") == "true" {
-		return fmt.Errorf("Deduplication enabled, residence must be HDD.")
+	if conflicts := vdiskConflicts(req, &readResp.Result); len(conflicts) > 0 {
+		return fmt.Errorf("A vdisk named %q already exists on the cluster with different settings:\n\n%s",
+			req.Name, strings.Join(conflicts, "\n"))
 	}
 
-	if d.Get("clusteredfilesystem") == "false" && d.Get("type") == "NFS" {
-		return fmt.Errorf("Disk type is NFS, clustered file system must be enabled.")
+	log.Printf("[DEBUG] Adopting existing vdisk %s", req.Name)
+	return nil
+}
+
+// vdiskConflicts lists the settings of vdisk that differ from the
+// configuration, as requested by req.
+func vdiskConflicts(req *client.AddVirtualDisk, vdisk *client.VirtualDisk) []string {
+	conflicts := []string{}
+	for _, conflict := range req.Conflicts(vdisk) {
+		attribute := strings.ToLower(conflict.Setting)
+		if conflict.Setting == "diskType" {
+			attribute = "type"
+		}
+		conflicts = append(conflicts, fmt.Sprintf("  %s: configured %v, cluster has %v", attribute, conflict.Requested, conflict.Actual))
 	}
+	return conflicts
+}
 
-	if d.Get("clusteredfilesystem") == "true" && d.Get("blocksize") != "512" {
-		return fmt.Errorf("Block Size must be 512 when Clustered File System is enabled.")
+// resourceVdiskClone creates the disk as a clone of source_vdisk, or of its
+// snapshot source_snapshot, then grows it to the configured size.
+func resourceVdiskClone(ctx context.Context, d *schema.ResourceData, c *HedvigClient) error {
+	name := d.Get("name").(string)
+	source := d.Get("source_vdisk").(string)
+
+	_, err := c.CloneVirtualDisk(ctx, &client.CloneVirtualDisk{
+		Name:        name,
+		Source:      source,
+		Snapshot:    d.Get("source_snapshot").(string),
+		Description: d.Get("description").(string),
+	})
+	if client.IsAlreadyExists(err) {
+		return fmt.Errorf("A vdisk named %q already exists on the cluster", name)
+	}
+	if err != nil {
+		return fmt.Errorf("Error cloning vdisk %q from %q: %s", name, source, err)
 	}
 
-	if d.Get("scsi3pr") == "true" && d.Get("type") == "NFS" {
-		return fmt.Errorf("Clustered Shared Volumes (scsi3pr) not supported for NFS disks.")
+	readResp, err := c.VirtualDiskDetails(ctx, name)
+	if err != nil {
+		return fmt.Errorf("Error reading clone %q: %s", name, err)
 	}
+	d.SetId(buildID("vdisk", name, vdiskType(&readResp.Result)))
 
-	if d.Get("cacheenabled") == "false" && d.Get("deduplication") == "true" {
-		return fmt.Errorf("Client-side caching should be enabled when deduplication is.")
+	size := vdiskSize(d.Get("size").(int), d.Get("size_unit").(string))
+	if size.Bytes() < readResp.Result.SizeBytes() {
+		return fmt.Errorf("Clone %q is smaller than its source: size must be at least %d %s",
+			name, readResp.Result.Size.Value, readResp.Result.Size.Units)
+	}
+	if size.Bytes() > readResp.Result.SizeBytes() {
+		_, err = c.ResizeDisks(ctx, &client.ResizeDisks{VirtualDisks: []string{name}, Size: size})
+		if err != nil {
+			return fmt.Errorf("Error resizing clone %q: %s", name, err)
+		}
+		if err := waitForVdiskSize(ctx, c, name, size); err != nil {
+			return fmt.Errorf("Error waiting for clone %q to be resized: %s", name, err)
+		}
 	}
 
-	q := url.Values{}
-	q.Set("request", fmt.Sprintf("{type:AddVirtualDisk, category:VirtualDiskManagement, params:{name:'%s', size:{unit:'GB', value:%d}, diskType:%s, residence:%s, replicationFactor:%d, deduplication:%t, compressed:%s, blockSize:%s, scsi3pr:%s, cacheEnabled:%s, replicationPolicy:%s, clusteredFileSystem:%s, encryption:%s, description:'%s'}, sessionId:'%s'}", d.Get("name").(string), d.Get("size").(int), d.Get("type").(string), d.Get("residence"), d.Get("replicationfactor").(int), d.Get("deduplication"), compress, blocksize, d.Get("scsi3pr"), d.Get("cacheenabled"), d.Get("replicationpolicy").(string), d.Get("clusteredfilesystem"), d.Get("encryption"), d.Get("description"), sessionID))
-	u.RawQuery = q.Encode()
-	log.Printf("URL: %v", u.String())
+	if cacheEnabled := d.Get("cacheenabled").(bool); cacheEnabled != readResp.Result.CacheEnabled {
+		_, err = c.UpdateVirtualDisk(ctx, &client.UpdateVirtualDisk{VirtualDisk: name, CacheEnabled: &cacheEnabled})
+		if err != nil {
+			return fmt.Errorf("Error updating clone %q: %s", name, err)
+		}
+	}
+
+	return nil
+}
 
-	resp, err := http.Get(u.String())
+func resourceVdiskRead(d *schema.ResourceData, meta interface{}) error {
+	ctx, cancel := context.WithTimeout(context.Background(), d.Timeout(schema.TimeoutRead))
+	defer cancel()
+
+	id, err := parseID(d.Id(), "vdisk", "name", "type")
 	if err != nil {
 		return err
 	}
 
-	body, err := ioutil.ReadAll(resp.Body)
+	readResp, err := meta.(*HedvigClient).VirtualDiskDetails(ctx, id[0])
+	if client.IsNotFound(err) {
+		d.SetId("")
+		log.Printf("Vdisk not found, clearing from state")
+		return nil
+	}
 	if err != nil {
 		return err
 	}
 
-	createResp := createDiskResponse{}
-	err = json.Unmarshal(body, &createResp)
+	return flattenVdisk(d, &readResp.Result)
+}
+
+func resourceVdiskUpdate(d *schema.ResourceData, meta interface{}) error {
+	ctx, cancel := context.WithTimeout(context.Background(), d.Timeout(schema.TimeoutUpdate))
+	defer cancel()
+
+	id, err := parseID(d.Id(), "vdisk", "name", "type")
 	if err != nil {
 		return err
 	}
 
-	//TODO: check for better way of returning results
-	if len(createResp.Result) < 1 {
-		return errors.New(createResp.Message)
+	if d.HasChange("description") || d.HasChange("cacheenabled") {
+		req := &client.UpdateVirtualDisk{VirtualDisk: id[0]}
+		if d.HasChange("description") {
+			description := d.Get("description").(string)
+			req.Description = &description
+		}
+		if d.HasChange("cacheenabled") {
+			cacheEnabled := d.Get("cacheenabled").(bool)
+			req.CacheEnabled = &cacheEnabled
+		}
+
+		_, err := meta.(*HedvigClient).UpdateVirtualDisk(ctx, req)
+		if err != nil {
+			return fmt.Errorf("Error updating vdisk %q: %s", id[0], err)
+		}
 	}
 
-	if createResp.Result[0].Status != "ok" {
-		if strings.HasSuffix(createResp.Message, "Run setkmsinfo command") {
-			return fmt.Errorf("Cannot enable encryption without setting up KMS. Please refer to the Hedvig Encrypt360 Guide for assistance.")
+	if d.HasChange("residence") || d.HasChange("replicationfactor") || d.HasChange("replicationpolicy") {
+		req := &client.MigrateVirtualDisk{VirtualDisk: id[0]}
+		if d.HasChange("residence") {
+			req.Residence = vdiskSetting(d, "residence").(string)
+		}
+		if d.HasChange("replicationfactor") {
+			req.ReplicationFactor = vdiskSetting(d, "replicationfactor").(int)
+		}
+		if d.HasChange("replicationpolicy") {
+			req.ReplicationPolicy = vdiskSetting(d, "replicationpolicy").(string)
+		}
+
+		_, err := meta.(*HedvigClient).MigrateVirtualDisk(ctx, req)
+		if err != nil {
+			return fmt.Errorf("Error migrating vdisk %q: %s", id[0], err)
+		}
+
+		if err := waitForVdiskMigration(ctx, meta.(*HedvigClient), req); err != nil {
+			return fmt.Errorf("Error waiting for vdisk %q to be migrated: %s", id[0], err)
 		}
-		return fmt.Errorf("Error creating vdisk %q: %s", d.Get("name").(string), createResp.Result[0].Message)
 	}
 
-	d.SetId("vdisk$" + d.Get("name").(string) + "$" + d.Get("type").(string))
+	if d.HasChange("size") || d.HasChange("size_unit") {
+		size := vdiskSize(d.Get("size").(int), d.Get("size_unit").(string))
+
+		readResp, err := meta.(*HedvigClient).VirtualDiskDetails(ctx, id[0])
+		if err != nil {
+			return err
+		}
+
+		if readResp.Result.SizeBytes() > size.Bytes() {
+			return errors.New("Cannot downsize a virtual disk")
+		}
+
+		if readResp.Result.SizeBytes() != size.Bytes() {
+			_, err = meta.(*HedvigClient).ResizeDisks(ctx, &client.ResizeDisks{
+				VirtualDisks: []string{id[0]},
+				Size:         size,
+			})
+			if err != nil {
+				return fmt.Errorf("Error resizing vdisk %q: %s", id[0], err)
+			}
+
+			if err := waitForVdiskSize(ctx, meta.(*HedvigClient), id[0], size); err != nil {
+				return fmt.Errorf("Error waiting for vdisk %q to be resized: %s", id[0], err)
+			}
+		}
+	}
 
 	return resourceVdiskRead(d, meta)
 }
 
-func resourceVdiskRead(d *schema.ResourceData, meta interface{}) error {
-	u := url.URL{}
-	u.Host = meta.(*HedvigClient).Node
-	u.Path = "/rest/"
-	u.Scheme = "http"
+func resourceVdiskDelete(d *schema.ResourceData, meta interface{}) error {
+	ctx, cancel := context.WithTimeout(context.Background(), d.Timeout(schema.TimeoutDelete))
+	defer cancel()
 
-	sessionID, err := GetSessionId(d, meta.(*HedvigClient))
+	id, err := parseID(d.Id(), "vdisk", "name", "type")
 	if err != nil {
 		return err
 	}
 
-	idSplit := strings.Split(d.Id(), "$")
-	log.Printf("idSplit: %v", idSplit)
-	if len(idSplit) != 3 {
-		return fmt.Errorf("Invalid ID: %s", d.Id())
+	if d.Get("deletion_protection").(bool) {
+		return fmt.Errorf("Refusing to delete vdisk %q while deletion_protection is set; set it to false and apply first", id[0])
 	}
 
-	q := url.Values{}
-	q.Set("request", fmt.Sprintf("{type:VirtualDiskDetails,category:VirtualDiskManagement,params:{virtualDisk:'%s'},sessionId:'%s'}", idSplit[1], sessionID))
-
-	u.RawQuery = q.Encode()
-	resp, err := http.Get(u.String())
+	err = resourceVdiskDetach(ctx, meta.(*HedvigClient), id[0], d.Get("force_detach").(bool))
+	if client.IsNotFound(err) {
+		log.Printf("Vdisk %s already deleted", id[0])
+		return nil
+	}
 	if err != nil {
 		return err
 	}
 
-	if resp.StatusCode == 404 {
-		return errors.New("Malformed query; aborting")
+	_, err = meta.(*HedvigClient).DeleteVDisk(ctx, &client.DeleteVDisk{
+		VirtualDisks: []string{id[0]},
+	})
+	if client.IsNotFound(err) {
+		log.Printf("Vdisk %s already deleted", id[0])
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("Error deleting vdisk: %s", err)
 	}
+	return nil
+}
 
-	body, err := ioutil.ReadAll(resp.Body)
+// resourceVdiskDetach refuses to let a disk that is still exported or
+// accessible to hosts be deleted, unless force is set, in which case its
+// LUNs, NFS exports and ACL entries are removed first. Errors reading the
+// disk are returned as is, so that a disk that is already gone can be
+// recognized.
+func resourceVdiskDetach(ctx context.Context, c *HedvigClient, name string, force bool) error {
+	exports, err := c.Exports(ctx, name)
 	if err != nil {
 		return err
 	}
-
-	readResp := readDiskResponse{}
-	err = json.Unmarshal(body, &readResp)
+	acl, err := c.GetACLInformation(ctx, name)
 	if err != nil {
 		return err
 	}
 
-	if readResp.Status == "warning" && strings.HasSuffix(readResp.Message, "t be found") {
-		d.SetId("")
-		log.Printf("Vdisk not found, clearing from state")
+	hosts := []string{}
+	for _, rec := range acl.Result {
+		hosts = append(hosts, rec.Host)
+	}
+	if !exports.Exported() && len(hosts) == 0 {
 		return nil
 	}
 
-	if readResp.Result.DiskType == "NFS_MASTER_DISK" {
-		d.Set("type", "NFS")
-	} else {
-		d.Set("type", readResp.Result.DiskType)
+	if !force {
+		return fmt.Errorf("Refusing to delete vdisk %q while it is attached (LUNs: %s; NFS: %s; ACL hosts: %s); detach it first or set force_detach",
+			name, listOrNone(exports.Luns), listOrNone(exports.Mounts), listOrNone(hosts))
 	}
-	d.Set("name", readResp.Result.VDiskName)
-	d.Set("size", readResp.Result.Size.Value)
 
+	log.Printf("[DEBUG] Detaching vdisk %s to delete it", name)
+	if err := c.Unexport(ctx, name, exports); err != nil {
+		return fmt.Errorf("Error unexporting vdisk %q: %s", name, err)
+	}
+	for _, rec := range acl.Result {
+		addresses := []string{}
+		for _, initiator := range rec.Initiator {
+			addresses = append(addresses, initiator.IP)
+		}
+		_, err := c.RemoveACLAccess(ctx, &client.RemoveACLAccess{
+			VirtualDisk: name,
+			Host:        rec.Host,
+			Address:     addresses,
+		})
+		if err != nil {
+			return fmt.Errorf("Error removing access of host %q to vdisk %q: %s", rec.Host, name, err)
+		}
+	}
 	return nil
 }
 
-// TODO: Verify and add tests
-func resourceVdiskUpdate(d *schema.ResourceData, meta interface{}) error {
-	u := url.URL{}
-	u.Host = meta.(*HedvigClient).Node
-	u.Path = "/rest/"
-	u.Scheme = "http"
+// vdiskOptions holds the planned settings of a vdisk that vdiskRules are
+// checked against.
+type vdiskOptions struct {
+	diskType            string
+	residence           string
+	blockSize           int
+	deduplication       bool
+	compressed          bool
+	clusteredFileSystem bool
+	scsi3pr             bool
+	cacheEnabled        bool
+}
 
-	q := url.Values{}
+func (o *vdiskOptions) nfs() bool { return strings.EqualFold(o.diskType, "NFS") }
+
+// vdiskRules are the combinations of settings the cluster refuses. Each names
+// the attribute to change to satisfy it.
+var vdiskRules = []struct {
+	attribute string
+	message   string
+	violated  func(o *vdiskOptions) bool
+}{
+	{
+		"compressed", "must be true when deduplication is enabled",
+		func(o *vdiskOptions) bool { return o.deduplication && !o.compressed },
+	},
+	{
+		"deduplication", "cannot be enabled for a BLOCK vdisk with clusteredfilesystem enabled",
+		func(o *vdiskOptions) bool { return o.deduplication && !o.nfs() && o.clusteredFileSystem },
+	},
+	{
+		"residence", "must be HDD when deduplication is enabled",
+		func(o *vdiskOptions) bool { return o.deduplication && !strings.EqualFold(o.residence, "HDD") },
+	},
+	{
+		"cacheenabled", "must be true when deduplication is enabled",
+		func(o *vdiskOptions) bool { return o.deduplication && !o.cacheEnabled },
+	},
+	{
+		"clusteredfilesystem", "must be true for NFS vdisks",
+		func(o *vdiskOptions) bool { return o.nfs() && !o.clusteredFileSystem },
+	},
+	{
+		"blocksize", "must be 512 for NFS vdisks",
+		func(o *vdiskOptions) bool { return o.nfs() && o.blockSize != 512 },
+	},
+	{
+		"blocksize", "must be 512 when clusteredfilesystem is enabled",
+		func(o *vdiskOptions) bool { return o.clusteredFileSystem && o.blockSize != 512 },
+	},
+	{
+		"scsi3pr", "is not supported for NFS vdisks",
+		func(o *vdiskOptions) bool { return o.nfs() && o.scsi3pr },
+	},
+}
 
-	sessionID, err := GetSessionId(d, meta.(*HedvigClient))
-	if err != nil {
-		return err
+func (o *vdiskOptions) validate() error {
+	for _, rule := range vdiskRules {
+		if rule.violated(o) {
+			return fmt.Errorf("%s: %s", rule.attribute, rule.message)
+		}
 	}
+	return nil
+}
 
-	idSplit := strings.Split(d.Id(), "$")
-	log.Printf("idSplit: %v", idSplit)
-	if len(idSplit) != 3 {
-		return fmt.Errorf("Invalid ID : %s", d.Id())
+func resourceVdiskCustomizeDiff(d *schema.ResourceDiff, meta interface{}) error {
+	c, _ := meta.(*HedvigClient)
+	if err := resourceVdiskCustomizeDiffName(d, c); err != nil {
+		return err
 	}
 
-	if d.HasChange("size") {
-		q.Set("request", fmt.Sprintf("{type:VirtualDiskDetails,category:VirtualDiskManagement,params:{virtualDisk:'%s'},sessionId:'%s'}", idSplit[1], sessionID))
-
-		u.RawQuery = q.Encode()
-
-		resp, err := http.Get(u.String())
-
+	if d.NewValueKnown("size") && d.NewValueKnown("size_unit") {
+		size := vdiskSize(d.Get("size").(int), d.Get("size_unit").(string))
+		sizeBytes, err := intBytes(size.Bytes())
 		if err != nil {
-			return err
+			return fmt.Errorf("size: %s", err)
 		}
-
-		body, err := ioutil.ReadAll(resp.Body)
-		if err != nil {
+		if err := d.SetNew("size_bytes", sizeBytes); err != nil {
 			return err
 		}
 
-		readResp := readDiskResponse{}
-		err = json.Unmarshal(body, &readResp)
-		if err != nil {
+		if err := resourceVdiskCustomizeDiffShrink(d, size); err != nil {
 			return err
 		}
+	}
 
-		if readResp.Result.Size.Value > d.Get("size").(int) {
-			return errors.New("Cannot downsize a virtual disk")
+	if err := resourceVdiskCustomizeDiffMigration(d); err != nil {
+		return err
+	}
+
+	if d.Get("source_snapshot").(string) != "" && d.Get("source_vdisk").(string) == "" {
+		return errors.New("source_snapshot: requires source_vdisk, the vdisk the snapshot was taken of")
+	}
+	if !d.NewValueKnown("source_vdisk") {
+		return nil
+	}
+	if d.Get("source_vdisk").(string) != "" && d.Id() == "" {
+		return resourceVdiskCustomizeDiffClone(d, c)
+	}
+
+	for _, k := range []string{"type", "residence", "blocksize", "deduplication", "compressed", "clusteredfilesystem", "scsi3pr", "cacheenabled"} {
+		if !d.NewValueKnown(k) {
+			// Checked again once the value is known.
+			return nil
 		}
+	}
 
-		q.Set("request", fmt.Sprintf("{type:ResizeDisks, category:VirtualDiskManagement, params:{virtualDisks:['%s'], size:{unit:'GB', value:%d}}, sessionId:'%s'}", idSplit[1], d.Get("size").(int),
-			sessionID))
-		u.RawQuery = q.Encode()
-		log.Printf("URL: %v", u.String())
+	blockSize, _ := parseBlockSize(vdiskSetting(d, "blocksize").(string))
+	o := &vdiskOptions{
+		diskType:            d.Get("type").(string),
+		residence:           vdiskSetting(d, "residence").(string),
+		blockSize:           blockSize,
+		deduplication:       d.Get("deduplication").(bool),
+		compressed:          d.Get("compressed").(bool),
+		clusteredFileSystem: d.Get("clusteredfilesystem").(bool),
+		scsi3pr:             d.Get("scsi3pr").(bool),
+		cacheEnabled:        d.Get("cacheenabled").(bool),
+	}
+	if err := o.validate(); err != nil {
+		return err
+	}
 
-		resp, err = http.Get(u.String())
+	if !d.NewValueKnown("replicationfactor") {
+		return nil
+	}
+	return resourceVdiskCustomizeDiffCapacity(d, c, vdiskSetting(d, "residence").(string), vdiskSetting(d, "replicationfactor").(int))
+}
 
-		if err != nil {
-			return err
-		}
+// resourceVdiskCustomizeDiffName checks the names of new disks against the
+// naming rules of the cluster and the provider's name_prefix. Disks already
+// in state, e.g. imported ones, keep their names.
+func resourceVdiskCustomizeDiffName(d *schema.ResourceDiff, c *HedvigClient) error {
+	if !d.NewValueKnown("name") {
+		return nil
+	}
+	if d.Id() != "" && !d.HasChange("name") {
+		return nil
+	}
 
-		body, err = ioutil.ReadAll(resp.Body)
-		if err != nil {
-			return err
-		}
+	name := d.Get("name").(string)
+	if _, es := validateVdiskName(name, "name"); len(es) > 0 {
+		return es[0]
+	}
+	if c != nil && c.NamePrefix != "" && !strings.HasPrefix(name, c.NamePrefix) {
+		return fmt.Errorf("name: %q must start with the provider's name_prefix %q", name, c.NamePrefix)
+	}
+	return nil
+}
 
-		updateResp := updateDiskResponse{}
-		err = json.Unmarshal(body, &updateResp)
-		if err != nil {
-			return err
+// resourceVdiskCustomizeDiffClone rejects settings of a new clone that
+// differ from the ones it inherits from its source. Settings left out of the
+// configuration take the source's values. The capacity of the clone is
+// checked at its final size.
+func resourceVdiskCustomizeDiffClone(d *schema.ResourceDiff, c *HedvigClient) error {
+	if c == nil {
+		return nil
+	}
+
+	source := d.Get("source_vdisk").(string)
+	resp, err := c.VirtualDiskDetails(context.Background(), source)
+	if err != nil {
+		// The source may only be created by this apply.
+		log.Printf("[DEBUG] Cannot read %q, the source of clone %q: %s", source, d.Get("name").(string), err)
+		return nil
+	}
+	vdisk := &resp.Result
+	blockSize, _ := parseBlockSize(d.Get("blocksize").(string))
+
+	inherited := []struct {
+		attribute string
+		actual    interface{}
+		equal     bool
+	}{
+		{"type", vdiskType(vdisk), strings.EqualFold(d.Get("type").(string), vdiskType(vdisk))},
+		{"residence", vdisk.Residence, strings.EqualFold(d.Get("residence").(string), vdisk.Residence)},
+		{"replicationfactor", vdisk.ReplicationFactor, d.Get("replicationfactor").(int) == vdisk.ReplicationFactor},
+		{"replicationpolicy", replicationPolicy(vdisk), strings.EqualFold(d.Get("replicationpolicy").(string), replicationPolicy(vdisk))},
+		{"deduplication", vdisk.Deduplication, d.Get("deduplication").(bool) == vdisk.Deduplication},
+		{"compressed", vdisk.Compressed, d.Get("compressed").(bool) == vdisk.Compressed},
+		{"blocksize", vdisk.BlockSize, blockSize == vdisk.BlockSize},
+		{"clusteredfilesystem", vdisk.ClusteredFileSystem, d.Get("clusteredfilesystem").(bool) == vdisk.ClusteredFileSystem},
+		{"scsi3pr", vdisk.Scsi3pr, d.Get("scsi3pr").(bool) == vdisk.Scsi3pr},
+		{"encryption", vdisk.Encryption, d.Get("encryption").(bool) == vdisk.Encryption},
+	}
+	for _, setting := range inherited {
+		if _, configured := d.GetOkExists(setting.attribute); configured && !setting.equal {
+			return fmt.Errorf("%s: clones inherit %v from their source %q; leave it unset or set it to that value",
+				setting.attribute, setting.actual, source)
 		}
+	}
+
+	return resourceVdiskCustomizeDiffCapacity(d, c, vdisk.Residence, vdisk.ReplicationFactor)
+}
 
-		if updateResp.Status != "ok" {
-			return fmt.Errorf("Error updating vdisk: %s", updateResp.Status)
+// resourceVdiskCustomizeDiffCapacity checks that the capacity a new or grown
+// disk takes, its size times its replication factor, leaves the configured
+// headroom free on its residence tier.
+func resourceVdiskCustomizeDiffCapacity(d *schema.ResourceDiff, c *HedvigClient, residence string, replicationFactor int) error {
+	if c == nil || c.CapacityCheck == "off" {
+		return nil
+	}
+	if !d.NewValueKnown("size") || !d.NewValueKnown("size_unit") {
+		return nil
+	}
+
+	size := vdiskSize(d.Get("size").(int), d.Get("size_unit").(string))
+	needed := size.Bytes() * int64(replicationFactor)
+	if d.Id() != "" {
+		oldSize, _ := d.GetChange("size")
+		oldUnit, _ := d.GetChange("size_unit")
+		oldResidence, _ := d.GetChange("residence")
+		oldReplicationFactor, _ := d.GetChange("replicationfactor")
+		if oldUnit.(string) == "" {
+			oldUnit = "GB"
 		}
+		if strings.EqualFold(oldResidence.(string), residence) {
+			needed -= vdiskSize(oldSize.(int), oldUnit.(string)).Bytes() * int64(oldReplicationFactor.(int))
+		}
+	}
+	if needed <= 0 {
+		return nil
+	}
+
+	resp, err := c.ClusterCapacity(context.Background())
+	if err != nil {
+		log.Printf("[WARN] Cannot check the capacity of the cluster: %s", err)
+		return nil
+	}
+	tier := resp.Tier(residence)
+	if tier == nil {
+		log.Printf("[WARN] Cluster reports no capacity for residence %s", residence)
+		return nil
+	}
 
-		log.Printf("body: %s", body)
+	reserved := tier.Total.Bytes() * int64(c.CapacityHeadroom) / 100
+	if tier.FreeBytes()-needed >= reserved {
+		return nil
 	}
 
-	return resourceVdiskRead(d, meta)
+	msg := fmt.Sprintf("size: vdisk %q needs %s on the %s tier (%d %s with replicationfactor %d), which leaves less than %d%% of it free (%s of %s free)",
+		d.Get("name").(string), formatBytes(needed), residence, size.Value, size.Unit, replicationFactor,
+		c.CapacityHeadroom, formatBytes(tier.FreeBytes()), formatBytes(tier.Total.Bytes()))
+	if c.CapacityCheck == "warn" {
+		// A plan cannot carry warnings, so this only reaches the log.
+		log.Printf("[WARN] %s", msg)
+		return nil
+	}
+	return errors.New(msg)
 }
 
-func resourceVdiskDelete(d *schema.ResourceData, meta interface{}) error {
-	u := url.URL{}
-	u.Host = meta.(*HedvigClient).Node
-	u.Path = "/rest/"
-	u.Scheme = "http"
+// resourceVdiskCustomizeDiffShrink refuses to plan a smaller size, which the
+// cluster cannot apply, unless the disk may be replaced instead.
+func resourceVdiskCustomizeDiffShrink(d *schema.ResourceDiff, size client.Size) error {
+	if d.Id() == "" || !(d.HasChange("size") || d.HasChange("size_unit")) {
+		return nil
+	}
 
-	sessionID, err := GetSessionId(d, meta.(*HedvigClient))
-	if err != nil {
-		return err
+	oldSize, _ := d.GetChange("size")
+	oldUnit, _ := d.GetChange("size_unit")
+	if oldUnit.(string) == "" {
+		oldUnit = "GB"
+	}
+	old := vdiskSize(oldSize.(int), oldUnit.(string))
+	if size.Bytes() >= old.Bytes() {
+		return nil
 	}
 
-	idSplit := strings.Split(d.Id(), "$")
-	log.Printf("idSplit: %v", idSplit)
-	if len(idSplit) != 3 {
-		return fmt.Errorf("Invalid ID: %s", d.Id())
+	if !d.Get("allow_replace_on_shrink").(bool) {
+		return fmt.Errorf("size: cannot shrink vdisk %q from %d %s to %d %s; set allow_replace_on_shrink to replace it instead",
+			d.Get("name").(string), old.Value, old.Unit, size.Value, size.Unit)
 	}
 
-	q := url.Values{}
-	q.Set("request", fmt.Sprintf("{type:DeleteVDisk, category:VirtualDiskManagement, params:{virtualDisks:['%s']}, sessionId:'%s'}}", idSplit[1], sessionID))
+	log.Printf("[DEBUG] Vdisk %s shrinks from %d %s to %d %s, replacing it", d.Id(), old.Value, old.Unit, size.Value, size.Unit)
+	if d.HasChange("size") {
+		return d.ForceNew("size")
+	}
+	return d.ForceNew("size_unit")
+}
 
-	u.RawQuery = q.Encode()
-	resp, err := http.Get(u.String())
-	if err != nil {
-		return err
+// waitForVdiskSize polls the disk until the cluster reports its new size.
+func waitForVdiskSize(ctx context.Context, c *HedvigClient, name string, size client.Size) error {
+	timeout := 10 * time.Minute
+	if deadline, ok := ctx.Deadline(); ok {
+		timeout = time.Until(deadline)
 	}
 
-	body, err := ioutil.ReadAll(resp.Body)
+	conf := &resource.StateChangeConf{
+		Pending: []string{"resizing"},
+		Target:  []string{"resized"},
+		Refresh: func() (interface{}, string, error) {
+			readResp, err := c.VirtualDiskDetails(ctx, name)
+			if err != nil {
+				return nil, "", err
+			}
+			if readResp.Result.SizeBytes() != size.Bytes() {
+				log.Printf("[DEBUG] Vdisk %s still reports %d %s", name, readResp.Result.Size.Value, readResp.Result.Size.Units)
+				return readResp, "resizing", nil
+			}
+			return readResp, "resized", nil
+		},
+		Timeout: timeout,
+	}
+
+	_, err := conf.WaitForState()
+	return err
+}
+
+// waitForVdiskMigration polls the disk until it has the settings requested
+// and its data has finished moving.
+func waitForVdiskMigration(ctx context.Context, c *HedvigClient, req *client.MigrateVirtualDisk) error {
+	timeout := 60 * time.Minute
+	if deadline, ok := ctx.Deadline(); ok {
+		timeout = time.Until(deadline)
+	}
+
+	conf := &resource.StateChangeConf{
+		Pending: []string{"migrating"},
+		Target:  []string{"migrated"},
+		Refresh: func() (interface{}, string, error) {
+			readResp, err := c.VirtualDiskDetails(ctx, req.VirtualDisk)
+			if err != nil {
+				return nil, "", err
+			}
+			if readResp.Result.Migrating || !req.Reached(&readResp.Result) {
+				log.Printf("[DEBUG] Vdisk %s is still being migrated", req.VirtualDisk)
+				return readResp, "migrating", nil
+			}
+			return readResp, "migrated", nil
+		},
+		Timeout: timeout,
+	}
+
+	_, err := conf.WaitForState()
+	return err
+}
+
+// resourceVdiskCustomizeDiffMigration replaces the disk instead of migrating
+// it in place when replace_on_migration is set.
+func resourceVdiskCustomizeDiffMigration(d *schema.ResourceDiff) error {
+	if d.Id() == "" || !d.Get("replace_on_migration").(bool) {
+		return nil
+	}
+	for _, k := range []string{"residence", "replicationfactor", "replicationpolicy"} {
+		if d.HasChange(k) {
+			if err := d.ForceNew(k); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// resourceVdiskImport accepts either the vdisk name or a full vdisk ID.
+func resourceVdiskImport(d *schema.ResourceData, meta interface{}) ([]*schema.ResourceData, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), d.Timeout(schema.TimeoutRead))
+	defer cancel()
+
+	name := d.Id()
+	if strings.HasPrefix(name, idVersion+"/") {
+		id, err := parseID(name, "vdisk", "name", "type")
+		if err != nil {
+			return nil, err
+		}
+		name = id[0]
+	} else if id, ok := parseLegacyID(name, "vdisk", 2); ok {
+		name = id[0]
+	}
+
+	readResp, err := meta.(*HedvigClient).VirtualDiskDetails(ctx, name)
+	if client.IsNotFound(err) {
+		return nil, fmt.Errorf("Cannot import vdisk %q: it does not exist on the cluster", name)
+	}
 	if err != nil {
+		return nil, fmt.Errorf("Error importing vdisk %q: %s", name, err)
+	}
+
+	vdisk := &readResp.Result
+	d.SetId(buildID("vdisk", vdisk.VDiskName, vdiskType(vdisk)))
+	if err := flattenVdisk(d, vdisk); err != nil {
+		return nil, err
+	}
+	// Settings of the provider rather than the cluster start at their defaults.
+	d.Set("allow_replace_on_shrink", false)
+	d.Set("replace_on_migration", false)
+	d.Set("adopt_existing", false)
+	d.Set("deletion_protection", false)
+	d.Set("force_detach", false)
+
+	return []*schema.ResourceData{d}, nil
+}
+
+// flattenVdisk sets every attribute of d from the disk's details. Where the
+// cluster reports a value in a different form than it was configured, e.g.
+// "FLASH" for "Flash" or 4096 for "4k", the configured form is kept.
+func flattenVdisk(d *schema.ResourceData, vdisk *client.VirtualDisk) error {
+	d.Set("name", vdisk.VDiskName)
+	d.Set("type", vdiskType(vdisk))
+	if err := flattenVdiskSize(d, vdisk); err != nil {
 		return err
 	}
+	d.Set("residence", normalizeChoice(d.Get("residence").(string), vdisk.Residence, vdiskResidences))
+	d.Set("replicationfactor", vdisk.ReplicationFactor)
+	d.Set("replicationpolicy", normalizeChoice(d.Get("replicationpolicy").(string), replicationPolicy(vdisk), vdiskReplicationPolicies))
+	d.Set("deduplication", vdisk.Deduplication)
+	d.Set("compressed", vdisk.Compressed)
+	d.Set("blocksize", normalizeBlockSize(d.Get("blocksize").(string), vdisk.BlockSize))
+	d.Set("clusteredfilesystem", vdisk.ClusteredFileSystem)
+	d.Set("scsi3pr", vdisk.Scsi3pr)
+	d.Set("cacheenabled", vdisk.CacheEnabled)
+	d.Set("encryption", vdisk.Encryption)
+	d.Set("description", vdisk.Description)
+	return nil
+}
 
-	deleteResp := deleteDiskResponse{}
-	err = json.Unmarshal(body, &deleteResp)
+// flattenVdiskSize sets size in the configured unit if the reported size is
+// a whole number of it, and in GB otherwise.
+func flattenVdiskSize(d *schema.ResourceData, vdisk *client.VirtualDisk) error {
+	bytes := vdisk.SizeBytes()
+	if bytes == 0 {
+		log.Printf("[WARN] Vdisk %s has a size in unknown units %q", vdisk.VDiskName, vdisk.Size.Units)
+		d.Set("size", vdisk.Size.Value)
+		d.Set("size_unit", vdisk.Size.Units)
+		return nil
+	}
+	sizeBytes, err := intBytes(bytes)
 	if err != nil {
-		return err
+		return fmt.Errorf("Error reading the size of vdisk %q: %s", vdisk.VDiskName, err)
 	}
 
-	if deleteResp.Result[0].Status != "ok" {
-		return fmt.Errorf("Error deleting vdisk: %s", deleteResp.Result[0].Message)
+	unit, _ := d.Get("size_unit").(string)
+	if unit == "" {
+		unit = vdisk.Size.Units
+	}
+	if !(strings.EqualFold(unit, "GB") || strings.EqualFold(unit, "TB")) || bytes%client.UnitBytes(unit) != 0 {
+		unit = "GB"
 	}
+
+	d.Set("size", int(bytes/client.UnitBytes(unit)))
+	d.Set("size_unit", unit)
+	d.Set("size_bytes", sizeBytes)
 	return nil
 }
+
+// intBytes converts a size in bytes for an int attribute, which cannot hold
+// 2 GB or more on 32-bit platforms.
+func intBytes(bytes int64) (int, error) {
+	if int64(int(bytes)) != bytes {
+		return 0, fmt.Errorf("%s does not fit in size_bytes on a 32-bit platform", formatBytes(bytes))
+	}
+	return int(bytes), nil
+}
+
+// formatBytes formats a capacity in GB, or in TB once it reaches one.
+func formatBytes(bytes int64) string {
+	if bytes >= client.UnitBytes("TB") {
+		return fmt.Sprintf("%.1f TB", float64(bytes)/float64(client.UnitBytes("TB")))
+	}
+	return fmt.Sprintf("%.1f GB", float64(bytes)/float64(client.UnitBytes("GB")))
+}
+
+func vdiskSize(size int, unit string) client.Size {
+	return client.Size{Unit: strings.ToUpper(unit), Value: size}
+}
+
+var (
+	vdiskResidences          = []string{"Flash", "HDD"}
+	vdiskReplicationPolicies = []string{"Agnostic", "DataCenterAware", "RackAware"}
+)
+
+// vdiskDefaults are the settings a new disk gets where they are not
+// configured. Clones take them from their source instead.
+//
+// The settings are not computed, so that one left out of the configuration
+// reads as unset rather than as the value in state: the default of a disk
+// changed outside of Terraform then shows as a change in the plan.
+var vdiskDefaults = map[string]interface{}{
+	"residence":           "HDD",
+	"replicationfactor":   3,
+	"replicationpolicy":   "Agnostic",
+	"deduplication":       false,
+	"compressed":          false,
+	"blocksize":           "4096",
+	"clusteredfilesystem": false,
+	"scsi3pr":             false,
+	"encryption":          false,
+}
+
+// vdiskSetting returns setting k of a disk, or its default where it is not
+// configured.
+func vdiskSetting(d interface {
+	Get(string) interface{}
+}, k string) interface{} {
+	switch v := d.Get(k).(type) {
+	case string:
+		if v == "" {
+			return vdiskDefaults[k]
+		}
+	case int:
+		if v == 0 {
+			return vdiskDefaults[k]
+		}
+	}
+	return d.Get(k)
+}
+
+// suppressVdiskUnset hides the diff of a setting left out of the
+// configuration while the disk has the value it gets by default: the
+// default on a new disk, and any value on a clone, which inherits it from
+// its source.
+func suppressVdiskUnset(k, old, new string, d *schema.ResourceData) bool {
+	// Settings left out of the configuration read as their zero value, so
+	// an unset bool is the same as false, which is also its default.
+	if new != "" && new != "0" && new != "false" {
+		return false
+	}
+	if d.Get("source_vdisk").(string) != "" {
+		return true
+	}
+	if k == "blocksize" {
+		size, _ := parseBlockSize(old)
+		defaultSize, _ := parseBlockSize(vdiskDefaults[k].(string))
+		return size == defaultSize
+	}
+	return strings.EqualFold(old, fmt.Sprint(vdiskDefaults[k]))
+}
+
+// The naming rules of the cluster for vdisks.
+const vdiskNameMaxLength = 64
+
+var (
+	vdiskNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_-]*$`)
+	// vdiskReservedPrefixes are used by the cluster for its own disks.
+	vdiskReservedPrefixes = []string{"hedvig"}
+)
+
+func validateVdiskName(v interface{}, k string) (ws []string, es []error) {
+	name := v.(string)
+	if len(name) > vdiskNameMaxLength {
+		es = append(es, fmt.Errorf("%q must be at most %d characters long, got %d", k, vdiskNameMaxLength, len(name)))
+	}
+	if !vdiskNamePattern.MatchString(name) {
+		es = append(es, fmt.Errorf("%q must start with a letter or digit and contain only letters, digits, \"-\" and \"_\", got %q", k, name))
+	}
+	for _, prefix := range vdiskReservedPrefixes {
+		if strings.HasPrefix(strings.ToLower(name), prefix) {
+			es = append(es, fmt.Errorf("%q must not start with %q, which is reserved by the cluster", k, prefix))
+		}
+	}
+	return
+}
+
+// normalizeChoice returns current if it names the same choice as the
+// cluster's value, and otherwise the canonical spelling of that value.
+func normalizeChoice(current, value string, choices []string) string {
+	if strings.EqualFold(current, value) {
+		return current
+	}
+	for _, c := range choices {
+		if strings.EqualFold(c, value) {
+			return c
+		}
+	}
+	return value
+}
+
+func normalizeBlockSize(current string, value int) string {
+	if size, err := parseBlockSize(current); err == nil && size == value {
+		return current
+	}
+	return strconv.Itoa(value)
+}
+
+// replicationPolicy maps the cluster's name for the default policy to the
+// one accepted in configuration.
+func replicationPolicy(vdisk *client.VirtualDisk) string {
+	if strings.EqualFold(vdisk.ReplicationPolicy, "RackUnaware") {
+		return "Agnostic"
+	}
+	return vdisk.ReplicationPolicy
+}
+
+// vdiskType maps the disk type reported by the cluster to the one used in
+// configuration.
+func vdiskType(vdisk *client.VirtualDisk) string {
+	if vdisk.DiskType == "NFS_MASTER_DISK" {
+		return "NFS"
+	}
+	return vdisk.DiskType
+}
+
+func parseBlockSize(blocksize string) (int, error) {
+	switch strings.ToLower(blocksize) {
+	case "4k":
+		return 4096, nil
+	case "64k":
+		return 65536, nil
+	}
+	return strconv.Atoi(blocksize)
+}
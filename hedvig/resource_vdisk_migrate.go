@@ -0,0 +1,56 @@
+package hedvig
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+
+	"github.com/hashicorp/terraform/helper/schema"
+)
+
+// resourceVdiskV0 is the schema of hedvig_vdisk before its flags became
+// booleans. Only the attribute types matter for decoding old state.
+func resourceVdiskV0() *schema.Resource {
+	return &schema.Resource{
+		Schema: map[string]*schema.Schema{
+			"name":                {Type: schema.TypeString, Required: true},
+			"size":                {Type: schema.TypeInt, Required: true},
+			"residence":           {Type: schema.TypeString, Optional: true},
+			"type":                {Type: schema.TypeString, Required: true},
+			"replicationfactor":   {Type: schema.TypeInt, Optional: true},
+			"deduplication":       {Type: schema.TypeBool, Optional: true},
+			"compressed":          {Type: schema.TypeString, Optional: true},
+			"blocksize":           {Type: schema.TypeString, Optional: true},
+			"clusteredfilesystem": {Type: schema.TypeString, Optional: true},
+			"scsi3pr":             {Type: schema.TypeString, Optional: true},
+			"cacheenabled":        {Type: schema.TypeString, Optional: true},
+			"encryption":          {Type: schema.TypeString, Optional: true},
+			"description":         {Type: schema.TypeString, Optional: true},
+			"replicationpolicy":   {Type: schema.TypeString, Optional: true},
+		},
+	}
+}
+
+// resourceVdiskStateUpgradeV0 converts the "true"/"false" strings of
+// version 0 to booleans.
+func resourceVdiskStateUpgradeV0(rawState map[string]interface{}, meta interface{}) (map[string]interface{}, error) {
+	for _, k := range []string{"compressed", "clusteredfilesystem", "scsi3pr", "cacheenabled", "encryption"} {
+		v, ok := rawState[k].(string)
+		if !ok {
+			continue
+		}
+		if v == "" {
+			rawState[k] = false
+			continue
+		}
+
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return nil, fmt.Errorf("Error upgrading %s of vdisk %v: %s", k, rawState["id"], err)
+		}
+		rawState[k] = b
+	}
+
+	log.Printf("[DEBUG] Upgraded vdisk state to version 1: %v", rawState)
+	return rawState, nil
+}
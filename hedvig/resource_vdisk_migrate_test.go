@@ -0,0 +1,44 @@
+package hedvig
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestUnitHedvigVdisk_stateUpgradeV0(t *testing.T) {
+	v0 := map[string]interface{}{
+		"id":                  "vdisk$unit-vdisk$BLOCK",
+		"name":                "unit-vdisk",
+		"size":                9,
+		"deduplication":       true,
+		"compressed":          "true",
+		"clusteredfilesystem": "false",
+		"scsi3pr":             "TRUE",
+		"cacheenabled":        "true",
+		"encryption":          "",
+	}
+	expected := map[string]interface{}{
+		"id":                  "vdisk$unit-vdisk$BLOCK",
+		"name":                "unit-vdisk",
+		"size":                9,
+		"deduplication":       true,
+		"compressed":          true,
+		"clusteredfilesystem": false,
+		"scsi3pr":             true,
+		"cacheenabled":        true,
+		"encryption":          false,
+	}
+
+	actual, err := resourceVdiskStateUpgradeV0(v0, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Fatalf("expected %v, got %v", expected, actual)
+	}
+
+	_, err = resourceVdiskStateUpgradeV0(map[string]interface{}{"compressed": "yes"}, nil)
+	if err == nil {
+		t.Fatalf("expected an error for an invalid flag")
+	}
+}
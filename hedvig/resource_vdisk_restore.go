@@ -0,0 +1,140 @@
+package hedvig
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/terraform/helper/schema"
+	"github.com/terraform-providers/terraform-provider-hedvig/hedvig/client"
+)
+
+// resourceVdiskRestore rolls a vdisk back to a snapshot whenever it is
+// created. Every argument forces a new resource, so changing the snapshot or
+// the trigger runs the rollback again.
+func resourceVdiskRestore() *schema.Resource {
+	return &schema.Resource{
+		Create: resourceVdiskRestoreCreate,
+		Read:   resourceVdiskRestoreRead,
+		Delete: resourceVdiskRestoreDelete,
+
+		Timeouts: &schema.ResourceTimeout{
+			Create: schema.DefaultTimeout(20 * time.Minute),
+			Read:   schema.DefaultTimeout(5 * time.Minute),
+		},
+
+		Schema: map[string]*schema.Schema{
+			"vdisk": {
+				Type:     schema.TypeString,
+				Required: true,
+				ForceNew: true,
+			},
+			"snapshot": {
+				Type:     schema.TypeString,
+				Required: true,
+				ForceNew: true,
+			},
+			"trigger": {
+				Type:     schema.TypeString,
+				Optional: true,
+				ForceNew: true,
+			},
+			"force_unexport": {
+				Type:     schema.TypeBool,
+				Optional: true,
+				Default:  false,
+				ForceNew: true,
+			},
+			"restored_at": {
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+		},
+	}
+}
+
+func resourceVdiskRestoreCreate(d *schema.ResourceData, meta interface{}) error {
+	ctx, cancel := context.WithTimeout(context.Background(), d.Timeout(schema.TimeoutCreate))
+	defer cancel()
+
+	c := meta.(*HedvigClient)
+	vdisk := d.Get("vdisk").(string)
+	snapshot := d.Get("snapshot").(string)
+
+	exports, err := c.Exports(ctx, vdisk)
+	if err != nil {
+		return fmt.Errorf("Error reading exports of vdisk %q: %s", vdisk, err)
+	}
+	if exports.Exported() {
+		if !d.Get("force_unexport").(bool) {
+			return fmt.Errorf("Refusing to restore vdisk %q while it is exported (LUNs: %s; NFS: %s); unexport it first or set force_unexport",
+				vdisk, listOrNone(exports.Luns), listOrNone(exports.Mounts))
+		}
+
+		log.Printf("[DEBUG] Unexporting vdisk %s to restore it", vdisk)
+		if err := c.Unexport(ctx, vdisk, exports); err != nil {
+			return fmt.Errorf("Error unexporting vdisk %q: %s", vdisk, err)
+		}
+	}
+
+	_, err = c.RollbackVirtualDisk(ctx, &client.RollbackVirtualDisk{
+		VirtualDisk: vdisk,
+		Snapshot:    snapshot,
+	})
+	if err != nil {
+		err = fmt.Errorf("Error restoring vdisk %q to snapshot %q: %s", vdisk, snapshot, err)
+	}
+
+	// Export the disk again even if the rollback failed, so that it is not
+	// left unavailable.
+	if exports.Exported() {
+		log.Printf("[DEBUG] Exporting vdisk %s again", vdisk)
+		if reexportErr := c.Reexport(ctx, vdisk, exports); reexportErr != nil {
+			reexportErr = fmt.Errorf("Error exporting vdisk %q again: %s", vdisk, reexportErr)
+			if err != nil {
+				return fmt.Errorf("%s; %s", err, reexportErr)
+			}
+			return reexportErr
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	d.SetId(buildID("restore", vdisk, snapshot))
+	d.Set("restored_at", time.Now().UTC().Format(time.RFC3339))
+
+	return resourceVdiskRestoreRead(d, meta)
+}
+
+func resourceVdiskRestoreRead(d *schema.ResourceData, meta interface{}) error {
+	ctx, cancel := context.WithTimeout(context.Background(), d.Timeout(schema.TimeoutRead))
+	defer cancel()
+
+	_, err := meta.(*HedvigClient).VirtualDiskDetails(ctx, d.Get("vdisk").(string))
+	if client.IsNotFound(err) {
+		d.SetId("")
+		log.Printf("Vdisk of restore %s not found, clearing from state", d.Id())
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("Error reading vdisk %q: %s", d.Get("vdisk").(string), err)
+	}
+
+	return nil
+}
+
+// resourceVdiskRestoreDelete only forgets the restore; the contents of the
+// disk are not changed.
+func resourceVdiskRestoreDelete(d *schema.ResourceData, meta interface{}) error {
+	return nil
+}
+
+func listOrNone(list []string) string {
+	if len(list) == 0 {
+		return "none"
+	}
+	return strings.Join(list, ", ")
+}
@@ -0,0 +1,112 @@
+package hedvig
+
+import (
+	"fmt"
+	"regexp"
+	"testing"
+
+	"github.com/hashicorp/terraform/helper/resource"
+	"github.com/hashicorp/terraform/terraform"
+	"github.com/terraform-providers/terraform-provider-hedvig/hedvig/client/clienttest"
+)
+
+func TestUnitHedvigVdiskRestore_basic(t *testing.T) {
+	s := testUnitServer()
+	defer s.Close()
+
+	resource.UnitTest(t, resource.TestCase{
+		Providers: testAccProviders,
+		Steps: []resource.TestStep{
+			{
+				Config: testUnitConfig(s, testUnitHedvigVdiskRestoreConfig(false, "1", false)),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("hedvig_vdisk_restore.test", "id", "v2/restore/unit-restore/unit-restore-snap-1"),
+					resource.TestCheckResourceAttrSet("hedvig_vdisk_restore.test", "restored_at"),
+					testUnitCheckHedvigVdiskRestored(s, "unit-restore-snap-1", 1),
+				),
+			},
+			{
+				Config: testUnitConfig(s, testUnitHedvigVdiskRestoreConfig(false, "2", false)),
+				Check:  testUnitCheckHedvigVdiskRestored(s, "unit-restore-snap-1", 2),
+			},
+		},
+	})
+}
+
+func TestUnitHedvigVdiskRestore_exported(t *testing.T) {
+	s := testUnitServer()
+	defer s.Close()
+
+	resource.UnitTest(t, resource.TestCase{
+		Providers: testAccProviders,
+		Steps: []resource.TestStep{
+			{
+				Config:      testUnitConfig(s, testUnitHedvigVdiskRestoreConfig(true, "1", false)),
+				ExpectError: regexp.MustCompile(`Refusing to restore vdisk "unit-restore" while it is exported \(LUNs: iscsi.example.com; NFS: none\)`),
+			},
+			{
+				Config: testUnitConfig(s, testUnitHedvigVdiskRestoreConfig(true, "1", true)),
+				Check: resource.ComposeTestCheckFunc(
+					testUnitCheckHedvigVdiskRestored(s, "unit-restore-snap-1", 1),
+					func(*terraform.State) error {
+						vdisk := s.VDisk("unit-restore")
+						if len(vdisk.Luns) != 1 || vdisk.Luns[0] != "iscsi.example.com" {
+							return fmt.Errorf("expected the LUN to be mapped again, got %v", vdisk.Luns)
+						}
+						return nil
+					},
+				),
+			},
+		},
+	})
+}
+
+// testUnitHedvigVdiskRestoreConfig restores a snapshot of a vdisk, which is
+// exported as a LUN first if exported is set.
+func testUnitHedvigVdiskRestoreConfig(exported bool, trigger string, forceUnexport bool) string {
+	lun, dependsOn := "", ""
+	if exported {
+		lun = `
+resource "hedvig_lun" "test" {
+  vdisk = "${hedvig_vdisk.test.name}"
+  controller = "iscsi.example.com"
+}
+`
+		dependsOn = `"hedvig_lun.test"`
+	}
+
+	return fmt.Sprintf(`
+resource "hedvig_vdisk" "test" {
+  name = "unit-restore"
+  size = 9
+  type = "BLOCK"
+}
+
+resource "hedvig_snapshot" "test" {
+  vdisk = "${hedvig_vdisk.test.name}"
+}
+%s
+resource "hedvig_vdisk_restore" "test" {
+  vdisk = "${hedvig_snapshot.test.vdisk}"
+  snapshot = "${hedvig_snapshot.test.name}"
+  trigger = "%s"
+  force_unexport = %t
+  depends_on = [%s]
+}
+`, lun, trigger, forceUnexport, dependsOn)
+}
+
+func testUnitCheckHedvigVdiskRestored(s *clienttest.Server, snapshot string, times int) resource.TestCheckFunc {
+	return func(*terraform.State) error {
+		vdisk := s.VDisk("unit-restore")
+		if vdisk == nil || vdisk.RolledBackTo != snapshot {
+			return fmt.Errorf("vdisk not rolled back to %s: %#v", snapshot, vdisk)
+		}
+		s.Lock()
+		defer s.Unlock()
+		if n := s.Requests["RollbackVirtualDisk"]; n != times {
+			return fmt.Errorf("expected %d rollbacks, got %d", times, n)
+		}
+		return nil
+	}
+}
@@ -4,10 +4,14 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"regexp"
+	"strings"
 	"testing"
 
 	"github.com/hashicorp/terraform/helper/resource"
 	"github.com/hashicorp/terraform/terraform"
+	"github.com/terraform-providers/terraform-provider-hedvig/hedvig/client"
+	"github.com/terraform-providers/terraform-provider-hedvig/hedvig/client/clienttest"
 )
 
 func TestAccHedvigVdisk(t *testing.T) {
@@ -47,6 +51,8 @@ resource "hedvig_vdisk" "test-vdisk2" {
   name = "%s"
   size = 11
   type = "NFS"
+  blocksize = "512"
+  clusteredfilesystem = true
 }
 `, os.Getenv("HV_TESTNODE"), os.Getenv("HV_TESTUSER"), os.Getenv("HV_TESTPASS"),
 	genRandomVdiskName(),
@@ -97,3 +103,1218 @@ func testAccCheckHedvigVdiskSize(n string) resource.TestCheckFunc {
 		return errors.New("Unknown problem with size of vdisk")
 	}
 }
+
+func TestUnitHedvigVdisk_basic(t *testing.T) {
+	s := testUnitServer()
+	defer s.Close()
+
+	resource.UnitTest(t, resource.TestCase{
+		Providers:    testAccProviders,
+		CheckDestroy: testUnitCheckHedvigVdiskDestroyed(s),
+		Steps: []resource.TestStep{
+			{
+				Config: testUnitConfig(s, testUnitHedvigVdiskConfig(9)),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("hedvig_vdisk.test", "id", "v2/vdisk/unit-vdisk/BLOCK"),
+					resource.TestCheckResourceAttr("hedvig_vdisk.test", "size", "9"),
+					resource.TestCheckResourceAttr("hedvig_vdisk.nfs", "type", "NFS"),
+					testUnitCheckHedvigVdiskSize(s, "unit-vdisk", 9),
+				),
+			},
+			{
+				Config: testUnitConfig(s, testUnitHedvigVdiskConfig(12)),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("hedvig_vdisk.test", "size", "12"),
+					testUnitCheckHedvigVdiskSize(s, "unit-vdisk", 12),
+				),
+			},
+		},
+	})
+}
+
+func TestUnitHedvigVdisk_disappears(t *testing.T) {
+	s := testUnitServer()
+	defer s.Close()
+
+	resource.UnitTest(t, resource.TestCase{
+		Providers:    testAccProviders,
+		CheckDestroy: testUnitCheckHedvigVdiskDestroyed(s),
+		Steps: []resource.TestStep{
+			{
+				Config: testUnitConfig(s, testUnitHedvigVdiskConfig(9)),
+				Check: func(*terraform.State) error {
+					s.Lock()
+					defer s.Unlock()
+					delete(s.VDisks, "unit-vdisk")
+					return nil
+				},
+				ExpectNonEmptyPlan: true,
+			},
+		},
+	})
+}
+
+func TestUnitHedvigVdisk_kms(t *testing.T) {
+	s := testUnitServer()
+	defer s.Close()
+
+	resource.UnitTest(t, resource.TestCase{
+		Providers: testAccProviders,
+		Steps: []resource.TestStep{
+			{
+				Config: testUnitConfig(s, `
+resource "hedvig_vdisk" "test" {
+  name = "unit-encrypted"
+  size = 9
+  type = "BLOCK"
+  encryption = true
+}
+`),
+				ExpectError: regexp.MustCompile("without setting up KMS"),
+			},
+		},
+	})
+}
+
+func TestUnitHedvigVdisk_import(t *testing.T) {
+	s := testUnitServer()
+	defer s.Close()
+
+	resource.UnitTest(t, resource.TestCase{
+		Providers:    testAccProviders,
+		CheckDestroy: testUnitCheckHedvigVdiskDestroyed(s),
+		Steps: []resource.TestStep{
+			{
+				Config: testUnitConfig(s, testUnitHedvigVdiskConfig(9)),
+			},
+			{
+				Config:            testUnitConfig(s, testUnitHedvigVdiskConfig(9)),
+				ResourceName:      "hedvig_vdisk.test",
+				ImportState:       true,
+				ImportStateId:     "unit-vdisk",
+				ImportStateVerify: true,
+			},
+			{
+				Config:            testUnitConfig(s, testUnitHedvigVdiskConfig(9)),
+				ResourceName:      "hedvig_vdisk.nfs",
+				ImportState:       true,
+				ImportStateVerify: true,
+			},
+		},
+	})
+}
+
+func TestUnitHedvigVdisk_importUnmanaged(t *testing.T) {
+	s := testUnitServer()
+	defer s.Close()
+
+	s.VDisks["ui-vdisk"] = &clienttest.VDisk{
+		AddVirtualDisk: client.AddVirtualDisk{
+			Name:              "ui-vdisk",
+			Size:              client.Size{Unit: "GB", Value: 20},
+			DiskType:          "BLOCK",
+			Residence:         "Flash",
+			ReplicationFactor: 2,
+			ReplicationPolicy: "RackAware",
+			Compressed:        true,
+			BlockSize:         65536,
+			Scsi3pr:           true,
+			CacheEnabled:      true,
+			Description:       "created in the UI",
+		},
+	}
+
+	resource.UnitTest(t, resource.TestCase{
+		Providers: testAccProviders,
+		Steps: []resource.TestStep{
+			{
+				Config: testUnitConfig(s, `
+resource "hedvig_vdisk" "test" {
+  name = "ui-vdisk"
+  size = 20
+  type = "BLOCK"
+}
+`),
+				ResourceName:  "hedvig_vdisk.test",
+				ImportState:   true,
+				ImportStateId: "ui-vdisk",
+				ImportStateCheck: func(states []*terraform.InstanceState) error {
+					if len(states) != 1 {
+						return fmt.Errorf("expected 1 imported vdisk, got %d", len(states))
+					}
+					expected := map[string]string{
+						"name":                "ui-vdisk",
+						"type":                "BLOCK",
+						"size":                "20",
+						"residence":           "Flash",
+						"replicationfactor":   "2",
+						"replicationpolicy":   "RackAware",
+						"deduplication":       "false",
+						"compressed":          "true",
+						"blocksize":           "65536",
+						"clusteredfilesystem": "false",
+						"scsi3pr":             "true",
+						"cacheenabled":        "true",
+						"encryption":          "false",
+						"description":         "created in the UI",
+					}
+					if states[0].ID != "v2/vdisk/ui-vdisk/BLOCK" {
+						return fmt.Errorf("unexpected ID %q", states[0].ID)
+					}
+					for k, v := range expected {
+						if got := states[0].Attributes[k]; got != v {
+							return fmt.Errorf("expected %s to be %q, got %q", k, v, got)
+						}
+					}
+					return nil
+				},
+			},
+			{
+				Config: testUnitConfig(s, `
+resource "hedvig_vdisk" "test" {
+  name = "ui-vdisk"
+  size = 20
+  type = "BLOCK"
+}
+`),
+				ResourceName:  "hedvig_vdisk.test",
+				ImportState:   true,
+				ImportStateId: "missing-vdisk",
+				ExpectError:   regexp.MustCompile("does not exist on the cluster"),
+			},
+		},
+	})
+}
+
+func TestUnitHedvigVdisk_drift(t *testing.T) {
+	s := testUnitServer()
+	defer s.Close()
+
+	// Spellings differing from what the cluster reports must not show as
+	// drift; changes made outside of Terraform must.
+	config := testUnitConfig(s, `
+resource "hedvig_vdisk" "test" {
+  name = "unit-drift"
+  size = 9
+  type = "BLOCK"
+  residence = "flash"
+  blocksize = "4k"
+  compressed = true
+  replicationpolicy = "agnostic"
+}
+
+resource "hedvig_vdisk" "defaults" {
+  name = "unit-drift-defaults"
+  size = 9
+  type = "BLOCK"
+}
+`)
+
+	resource.UnitTest(t, resource.TestCase{
+		Providers:    testAccProviders,
+		CheckDestroy: testUnitCheckHedvigVdiskDestroyed(s),
+		Steps: []resource.TestStep{
+			{
+				Config: config,
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("hedvig_vdisk.test", "residence", "flash"),
+					resource.TestCheckResourceAttr("hedvig_vdisk.test", "blocksize", "4k"),
+					resource.TestCheckResourceAttr("hedvig_vdisk.test", "compressed", "true"),
+					resource.TestCheckResourceAttr("hedvig_vdisk.test", "replicationpolicy", "agnostic"),
+				),
+			},
+			{
+				Config: config,
+				Check: func(*terraform.State) error {
+					s.Lock()
+					defer s.Unlock()
+					vdisk := s.VDisks["unit-drift"]
+					vdisk.Description = "changed in the UI"
+					vdisk.CacheEnabled = true
+					return nil
+				},
+				ExpectNonEmptyPlan: true,
+			},
+			{
+				// A setting left at its default is moved back to it.
+				Config: config,
+				Check: func(*terraform.State) error {
+					s.Lock()
+					defer s.Unlock()
+					s.VDisks["unit-drift-defaults"].Residence = "Flash"
+					return nil
+				},
+				ExpectNonEmptyPlan: true,
+			},
+			{
+				Config: config,
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("hedvig_vdisk.defaults", "residence", "HDD"),
+					func(*terraform.State) error {
+						if vdisk := s.VDisk("unit-drift-defaults"); vdisk.Residence != "HDD" {
+							return fmt.Errorf("vdisk not moved back to HDD: %#v", vdisk)
+						}
+						return nil
+					},
+				),
+			},
+		},
+	})
+}
+
+func TestUnitHedvigVdisk_sizeUnit(t *testing.T) {
+	s := testUnitServer()
+	defer s.Close()
+
+	config := func(size int, unit string) string {
+		return testUnitConfig(s, fmt.Sprintf(`
+resource "hedvig_vdisk" "test" {
+  name = "unit-size"
+  size = %d
+  size_unit = "%s"
+  type = "BLOCK"
+}
+`, size, unit))
+	}
+
+	resource.UnitTest(t, resource.TestCase{
+		Providers:    testAccProviders,
+		CheckDestroy: testUnitCheckHedvigVdiskDestroyed(s),
+		Steps: []resource.TestStep{
+			{
+				Config: config(1536, "GB"),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("hedvig_vdisk.test", "size", "1536"),
+					resource.TestCheckResourceAttr("hedvig_vdisk.test", "size_bytes", "1649267441664"),
+				),
+			},
+			{
+				Config: config(2, "TB"),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("hedvig_vdisk.test", "size", "2"),
+					resource.TestCheckResourceAttr("hedvig_vdisk.test", "size_unit", "TB"),
+					resource.TestCheckResourceAttr("hedvig_vdisk.test", "size_bytes", "2199023255552"),
+					func(*terraform.State) error {
+						// The UI reports the same capacity in GB.
+						s.Lock()
+						defer s.Unlock()
+						s.VDisks["unit-size"].Size = client.Size{Unit: "GB", Value: 2048}
+						return nil
+					},
+				),
+			},
+			{
+				Config:   config(2, "TB"),
+				PlanOnly: true,
+			},
+			{
+				Config: config(2, "tb"),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("hedvig_vdisk.test", "size", "2"),
+					resource.TestCheckResourceAttr("hedvig_vdisk.test", "size_unit", "tb"),
+				),
+			},
+		},
+	})
+
+	if n := s.Requests["ResizeDisks"]; n != 1 {
+		t.Fatalf("expected 1 resize, got %d", n)
+	}
+}
+
+func TestUnitHedvigVdisk_sessionExpired(t *testing.T) {
+	s := testUnitServer()
+	defer s.Close()
+	// The session expires right before the disk is created and again before
+	// it is grown, so both calls must log in again and be sent once more.
+	s.ExpireSessions["AddVirtualDisk"] = 1
+
+	resource.UnitTest(t, resource.TestCase{
+		Providers:    testAccProviders,
+		CheckDestroy: testUnitCheckHedvigVdiskDestroyed(s),
+		Steps: []resource.TestStep{
+			{
+				Config: testUnitConfig(s, testUnitHedvigVdiskResourceConfig("unit-session", 12)),
+				Check:  testUnitCheckHedvigVdiskSize(s, "unit-session", 12),
+			},
+			{
+				PreConfig: func() {
+					s.Lock()
+					defer s.Unlock()
+					s.ExpireSessions["ResizeDisks"] = 1
+				},
+				Config: testUnitConfig(s, testUnitHedvigVdiskResourceConfig("unit-session", 16)),
+				Check:  testUnitCheckHedvigVdiskSize(s, "unit-session", 16),
+			},
+		},
+	})
+
+	if n := s.Requests["AddVirtualDisk"]; n != 2 {
+		t.Fatalf("expected AddVirtualDisk to be sent again after logging in, got %d calls", n)
+	}
+	if n := s.Requests["ResizeDisks"]; n != 2 {
+		t.Fatalf("expected ResizeDisks to be sent again after logging in, got %d calls", n)
+	}
+	if n := s.Requests["Login"]; n < 2 {
+		t.Fatalf("expected to log in again, got %d logins", n)
+	}
+}
+
+func TestUnitHedvigVdisk_shrink(t *testing.T) {
+	s := testUnitServer()
+	defer s.Close()
+
+	config := func(size int, allowReplace bool) string {
+		return testUnitConfig(s, fmt.Sprintf(`
+resource "hedvig_vdisk" "test" {
+  name = "unit-shrink"
+  size = %d
+  type = "BLOCK"
+  allow_replace_on_shrink = %t
+}
+`, size, allowReplace))
+	}
+
+	resource.UnitTest(t, resource.TestCase{
+		Providers:    testAccProviders,
+		CheckDestroy: testUnitCheckHedvigVdiskDestroyed(s),
+		Steps: []resource.TestStep{
+			{
+				Config: config(12, false),
+			},
+			{
+				Config:      config(9, false),
+				PlanOnly:    true,
+				ExpectError: regexp.MustCompile(`size: cannot shrink vdisk "unit-shrink" from 12 GB to 9 GB`),
+			},
+			{
+				Config: config(9, true),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("hedvig_vdisk.test", "size", "9"),
+					testUnitCheckHedvigVdiskSize(s, "unit-shrink", 9),
+				),
+			},
+		},
+	})
+
+	if n := s.Requests["ResizeDisks"]; n != 0 {
+		t.Fatalf("expected the vdisk to be replaced rather than resized, got %d resizes", n)
+	}
+	if n := s.Requests["DeleteVDisk"]; n != 2 {
+		t.Fatalf("expected the vdisk to be replaced, got %d deletes", n)
+	}
+}
+
+func TestUnitHedvigVdisk_resizeWait(t *testing.T) {
+	s := testUnitServer()
+	defer s.Close()
+
+	resource.UnitTest(t, resource.TestCase{
+		Providers:    testAccProviders,
+		CheckDestroy: testUnitCheckHedvigVdiskDestroyed(s),
+		Steps: []resource.TestStep{
+			{
+				Config: testUnitConfig(s, testUnitHedvigVdiskConfig(9)),
+				Check: func(*terraform.State) error {
+					s.Lock()
+					defer s.Unlock()
+					s.VDisks["unit-vdisk"].ResizeLag = 2
+					return nil
+				},
+			},
+			{
+				Config: testUnitConfig(s, testUnitHedvigVdiskConfig(12)),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("hedvig_vdisk.test", "size", "12"),
+					resource.TestCheckResourceAttr("hedvig_vdisk.test", "size_bytes", "12884901888"),
+				),
+			},
+		},
+	})
+}
+
+func TestUnitHedvigVdisk_updateInPlace(t *testing.T) {
+	s := testUnitServer()
+	defer s.Close()
+
+	config := func(description string, cacheEnabled bool) string {
+		return testUnitConfig(s, fmt.Sprintf(`
+resource "hedvig_vdisk" "test" {
+  name = "unit-update"
+  size = 9
+  type = "BLOCK"
+  description = "%s"
+  cacheenabled = %t
+}
+`, description, cacheEnabled))
+	}
+
+	resource.UnitTest(t, resource.TestCase{
+		Providers:    testAccProviders,
+		CheckDestroy: testUnitCheckHedvigVdiskDestroyed(s),
+		Steps: []resource.TestStep{
+			{
+				Config: config("first", false),
+			},
+			{
+				Config: config("second", true),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("hedvig_vdisk.test", "description", "second"),
+					resource.TestCheckResourceAttr("hedvig_vdisk.test", "cacheenabled", "true"),
+					func(*terraform.State) error {
+						vdisk := s.VDisk("unit-update")
+						if vdisk.Description != "second" || !vdisk.CacheEnabled {
+							return fmt.Errorf("vdisk not updated on the cluster: %#v", vdisk)
+						}
+						return nil
+					},
+				),
+			},
+		},
+	})
+
+	if n := s.Requests["AddVirtualDisk"]; n != 1 {
+		t.Fatalf("expected the vdisk to be updated in place, got %d creates", n)
+	}
+}
+
+func TestUnitHedvigVdisk_clone(t *testing.T) {
+	s := testUnitServer()
+	defer s.Close()
+
+	config := testUnitConfig(s, `
+resource "hedvig_vdisk" "source" {
+  name = "unit-clone-source"
+  size = 12
+  type = "BLOCK"
+  residence = "Flash"
+  compressed = true
+  replicationfactor = 2
+}
+
+resource "hedvig_snapshot" "source" {
+  vdisk = "${hedvig_vdisk.source.name}"
+}
+
+resource "hedvig_vdisk" "clone" {
+  name = "unit-clone"
+  size = 20
+  type = "BLOCK"
+  source_vdisk = "${hedvig_vdisk.source.name}"
+  description = "dev copy"
+}
+
+resource "hedvig_vdisk" "from_snapshot" {
+  name = "unit-clone-snapshot"
+  size = 12
+  type = "BLOCK"
+  source_vdisk = "${hedvig_snapshot.source.vdisk}"
+  source_snapshot = "${hedvig_snapshot.source.name}"
+}
+`)
+
+	resource.UnitTest(t, resource.TestCase{
+		Providers:    testAccProviders,
+		CheckDestroy: testUnitCheckHedvigVdiskDestroyed(s),
+		Steps: []resource.TestStep{
+			{
+				Config: config,
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("hedvig_vdisk.clone", "id", "v2/vdisk/unit-clone/BLOCK"),
+					resource.TestCheckResourceAttr("hedvig_vdisk.clone", "residence", "Flash"),
+					resource.TestCheckResourceAttr("hedvig_vdisk.clone", "compressed", "true"),
+					resource.TestCheckResourceAttr("hedvig_vdisk.clone", "replicationfactor", "2"),
+					resource.TestCheckResourceAttr("hedvig_vdisk.clone", "size", "20"),
+					resource.TestCheckResourceAttr("hedvig_vdisk.clone", "description", "dev copy"),
+					resource.TestCheckResourceAttr("hedvig_vdisk.from_snapshot", "residence", "Flash"),
+					testUnitCheckHedvigVdiskSize(s, "unit-clone", 20),
+					testUnitCheckHedvigVdiskSize(s, "unit-clone-snapshot", 12),
+				),
+			},
+		},
+	})
+}
+
+func TestUnitHedvigVdisk_cloneMigrate(t *testing.T) {
+	s := testUnitServer()
+	defer s.Close()
+
+	config := func(residence string) string {
+		return testUnitConfig(s, fmt.Sprintf(`
+resource "hedvig_vdisk" "source" {
+  name = "unit-clone-source"
+  size = 12
+  type = "BLOCK"
+  residence = "Flash"
+}
+
+resource "hedvig_vdisk" "clone" {
+  name = "unit-clone"
+  size = 12
+  type = "BLOCK"
+  source_vdisk = "${hedvig_vdisk.source.name}"
+  %s
+}
+`, residence))
+	}
+
+	resource.UnitTest(t, resource.TestCase{
+		Providers:    testAccProviders,
+		CheckDestroy: testUnitCheckHedvigVdiskDestroyed(s),
+		Steps: []resource.TestStep{
+			{
+				Config: config(""),
+				Check:  resource.TestCheckResourceAttr("hedvig_vdisk.clone", "residence", "Flash"),
+			},
+			{
+				Config: config(`residence = "HDD"`),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("hedvig_vdisk.clone", "residence", "HDD"),
+					func(*terraform.State) error {
+						if vdisk := s.VDisk("unit-clone"); vdisk.Residence != "HDD" {
+							return fmt.Errorf("clone not migrated on the cluster: %#v", vdisk)
+						}
+						return nil
+					},
+				),
+			},
+			{
+				// Changed outside of Terraform.
+				PreConfig: func() {
+					s.Lock()
+					defer s.Unlock()
+					s.VDisks["unit-clone"].Residence = "Flash"
+				},
+				Config:             config(`residence = "HDD"`),
+				PlanOnly:           true,
+				ExpectNonEmptyPlan: true,
+			},
+		},
+	})
+
+	if n := s.Requests["CloneVirtualDisk"]; n != 1 {
+		t.Fatalf("expected the clone to be migrated in place, got %d clones", n)
+	}
+}
+
+func TestUnitHedvigVdisk_cloneInheritedSettings(t *testing.T) {
+	s := testUnitServer()
+	defer s.Close()
+
+	s.VDisks["unit-clone-source"] = &clienttest.VDisk{
+		AddVirtualDisk: client.AddVirtualDisk{
+			Name:              "unit-clone-source",
+			Size:              client.Size{Unit: "GB", Value: 12},
+			DiskType:          "BLOCK",
+			Residence:         "HDD",
+			ReplicationFactor: 3,
+			BlockSize:         4096,
+			Compressed:        true,
+		},
+	}
+
+	resource.UnitTest(t, resource.TestCase{
+		Providers: testAccProviders,
+		Steps: []resource.TestStep{
+			{
+				Config: testUnitConfig(s, `
+resource "hedvig_vdisk" "clone" {
+  name = "unit-clone"
+  size = 12
+  type = "BLOCK"
+  source_vdisk = "unit-clone-source"
+  compressed = false
+}
+`),
+				PlanOnly:    true,
+				ExpectError: regexp.MustCompile(`compressed: clones inherit true from their source "unit-clone-source"`),
+			},
+		},
+	})
+}
+
+func TestUnitHedvigVdisk_cloneSnapshotWithoutSource(t *testing.T) {
+	s := testUnitServer()
+	defer s.Close()
+
+	resource.UnitTest(t, resource.TestCase{
+		Providers: testAccProviders,
+		Steps: []resource.TestStep{
+			{
+				Config: testUnitConfig(s, `
+resource "hedvig_vdisk" "clone" {
+  name = "unit-clone"
+  size = 12
+  type = "BLOCK"
+  source_snapshot = "unit-clone-source-snap-1"
+}
+`),
+				PlanOnly:    true,
+				ExpectError: regexp.MustCompile("source_snapshot: requires source_vdisk"),
+			},
+		},
+	})
+}
+
+func TestUnitHedvigVdisk_cloneCollision(t *testing.T) {
+	s := testUnitServer()
+	defer s.Close()
+
+	disk := func(name, residence string) *clienttest.VDisk {
+		return &clienttest.VDisk{
+			AddVirtualDisk: client.AddVirtualDisk{
+				Name:              name,
+				Size:              client.Size{Unit: "GB", Value: 12},
+				DiskType:          "BLOCK",
+				Residence:         residence,
+				ReplicationFactor: 3,
+				ReplicationPolicy: "Agnostic",
+				BlockSize:         4096,
+			},
+			ACL: map[string][]string{},
+		}
+	}
+	// Another disk of the clone's name shows up while CloneVirtualDisk
+	// fails at the proxy, so the clone must not be taken for applied.
+	s.VDisks["unit-collision-source"] = disk("unit-collision-source", "Flash")
+	s.VDisks["unit-collision-clone"] = disk("unit-collision-clone", "HDD")
+	s.Unavailable["CloneVirtualDisk"] = 1
+
+	resource.UnitTest(t, resource.TestCase{
+		Providers: testAccProviders,
+		Steps: []resource.TestStep{
+			{
+				Config: testUnitConfig(s,
+					testUnitHedvigVdiskResourceConfig("unit-collision-clone", 12, `source_vdisk = "unit-collision-source"`),
+					"retry {\n    min_backoff = \"1ms\"\n  }"),
+				ExpectError: regexp.MustCompile(`Error cloning vdisk "unit-collision-clone" from "unit-collision-source": .*502`),
+			},
+		},
+	})
+
+	if n := s.Requests["CloneVirtualDisk"]; n != 1 {
+		t.Fatalf("expected CloneVirtualDisk not to be replayed, got %d calls", n)
+	}
+}
+
+func TestUnitHedvigVdisk_migrate(t *testing.T) {
+	s := testUnitServer()
+	defer s.Close()
+
+	resource.UnitTest(t, resource.TestCase{
+		Providers:    testAccProviders,
+		CheckDestroy: testUnitCheckHedvigVdiskDestroyed(s),
+		Steps: []resource.TestStep{
+			{
+				Config: testUnitConfig(s, testUnitHedvigVdiskResourceConfig("unit-migrate", 9, `residence = "HDD"`, "replicationfactor = 3", "replace_on_migration = false")),
+				Check: func(*terraform.State) error {
+					s.Lock()
+					defer s.Unlock()
+					s.VDisks["unit-migrate"].MigrationLag = 2
+					return nil
+				},
+			},
+			{
+				Config: testUnitConfig(s, testUnitHedvigVdiskResourceConfig("unit-migrate", 9, `residence = "Flash"`, "replicationfactor = 2", "replace_on_migration = false")),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("hedvig_vdisk.test", "residence", "Flash"),
+					resource.TestCheckResourceAttr("hedvig_vdisk.test", "replicationfactor", "2"),
+					func(*terraform.State) error {
+						vdisk := s.VDisk("unit-migrate")
+						if vdisk.Residence != "Flash" || vdisk.ReplicationFactor != 2 {
+							return fmt.Errorf("vdisk not migrated on the cluster: %#v", vdisk)
+						}
+						return nil
+					},
+				),
+			},
+		},
+	})
+
+	if n := s.Requests["AddVirtualDisk"]; n != 1 {
+		t.Fatalf("expected the vdisk to be migrated in place, got %d creates", n)
+	}
+}
+
+func TestUnitHedvigVdisk_migrateReplace(t *testing.T) {
+	s := testUnitServer()
+	defer s.Close()
+
+	resource.UnitTest(t, resource.TestCase{
+		Providers:    testAccProviders,
+		CheckDestroy: testUnitCheckHedvigVdiskDestroyed(s),
+		Steps: []resource.TestStep{
+			{
+				Config: testUnitConfig(s, testUnitHedvigVdiskResourceConfig("unit-migrate", 9, `residence = "HDD"`, "replicationfactor = 3", "replace_on_migration = true")),
+			},
+			{
+				Config: testUnitConfig(s, testUnitHedvigVdiskResourceConfig("unit-migrate", 9, `residence = "Flash"`, "replicationfactor = 3", "replace_on_migration = true")),
+				Check:  resource.TestCheckResourceAttr("hedvig_vdisk.test", "residence", "Flash"),
+			},
+		},
+	})
+
+	if n := s.Requests["MigrateVirtualDisk"]; n != 0 {
+		t.Fatalf("expected the vdisk to be replaced, got %d migrations", n)
+	}
+	if n := s.Requests["AddVirtualDisk"]; n != 2 {
+		t.Fatalf("expected the vdisk to be replaced, got %d creates", n)
+	}
+}
+
+func TestUnitHedvigVdisk_options(t *testing.T) {
+	block := vdiskOptions{diskType: "BLOCK", residence: "HDD", blockSize: 4096}
+	dedup := vdiskOptions{diskType: "BLOCK", residence: "HDD", blockSize: 4096, deduplication: true, compressed: true, cacheEnabled: true}
+	nfs := vdiskOptions{diskType: "NFS", residence: "HDD", blockSize: 512, clusteredFileSystem: true}
+
+	cases := []struct {
+		name     string
+		options  vdiskOptions
+		modify   func(o *vdiskOptions)
+		expected string
+	}{
+		{"block", block, nil, ""},
+		{"dedup", dedup, nil, ""},
+		{"nfs", nfs, nil, ""},
+		{"lower case nfs", nfs, func(o *vdiskOptions) { o.diskType = "nfs" }, ""},
+		{"dedup without compression", dedup, func(o *vdiskOptions) { o.compressed = false }, "compressed: must be true when deduplication is enabled"},
+		{"dedup with clustered block", dedup, func(o *vdiskOptions) { o.clusteredFileSystem = true; o.blockSize = 512 }, "deduplication: cannot be enabled for a BLOCK vdisk"},
+		{"dedup on flash", dedup, func(o *vdiskOptions) { o.residence = "Flash" }, "residence: must be HDD when deduplication is enabled"},
+		{"dedup without cache", dedup, func(o *vdiskOptions) { o.cacheEnabled = false }, "cacheenabled: must be true when deduplication is enabled"},
+		{"nfs without clustered fs", nfs, func(o *vdiskOptions) { o.clusteredFileSystem = false }, "clusteredfilesystem: must be true for NFS vdisks"},
+		{"nfs with 4k blocks", nfs, func(o *vdiskOptions) { o.blockSize = 4096 }, "blocksize: must be 512 for NFS vdisks"},
+		{"clustered fs with 4k blocks", block, func(o *vdiskOptions) { o.clusteredFileSystem = true }, "blocksize: must be 512 when clusteredfilesystem is enabled"},
+		{"nfs with scsi3pr", nfs, func(o *vdiskOptions) { o.scsi3pr = true }, "scsi3pr: is not supported for NFS vdisks"},
+		{"block with scsi3pr", block, func(o *vdiskOptions) { o.scsi3pr = true }, ""},
+	}
+
+	for _, tc := range cases {
+		o := tc.options
+		if tc.modify != nil {
+			tc.modify(&o)
+		}
+
+		err := o.validate()
+		switch {
+		case tc.expected == "" && err != nil:
+			t.Errorf("%s: unexpected error: %s", tc.name, err)
+		case tc.expected != "" && err == nil:
+			t.Errorf("%s: expected error %q, got none", tc.name, tc.expected)
+		case tc.expected != "" && !strings.Contains(err.Error(), tc.expected):
+			t.Errorf("%s: expected error %q, got %q", tc.name, tc.expected, err)
+		}
+	}
+}
+
+func TestUnitHedvigVdisk_optionsAtPlan(t *testing.T) {
+	s := testUnitServer()
+	defer s.Close()
+
+	resource.UnitTest(t, resource.TestCase{
+		Providers: testAccProviders,
+		Steps: []resource.TestStep{
+			{
+				Config: testUnitConfig(s, `
+resource "hedvig_vdisk" "test" {
+  name = "unit.options"
+  size = 9
+  type = "BLOCK"
+}
+`),
+				PlanOnly:    true,
+				ExpectError: regexp.MustCompile(`"name" must start with a letter or digit and contain only letters, digits, "-" and "_", got "unit.options"`),
+			},
+			{
+				Config: testUnitConfig(s, `
+resource "hedvig_vdisk" "test" {
+  name = "unit-options"
+  size = 9
+  type = "NFS"
+}
+`),
+				PlanOnly:    true,
+				ExpectError: regexp.MustCompile("clusteredfilesystem: must be true for NFS vdisks"),
+			},
+		},
+	})
+
+	if s.Requests["AddVirtualDisk"] != 0 {
+		t.Fatalf("expected no vdisk to be created, got %d AddVirtualDisk requests", s.Requests["AddVirtualDisk"])
+	}
+}
+
+func TestUnitHedvigVdisk_name(t *testing.T) {
+	cases := []struct {
+		name     string
+		expected string
+	}{
+		{"data-01_backup", ""},
+		{"Data", ""},
+		{strings.Repeat("a", 64), ""},
+		{strings.Repeat("a", 65), "must be at most 64 characters long, got 65"},
+		{"data.01", "must start with a letter or digit and contain only letters"},
+		{"price$list", "must start with a letter or digit and contain only letters"},
+		{"-data", "must start with a letter or digit and contain only letters"},
+		{"", "must start with a letter or digit and contain only letters"},
+		{"HedvigMeta", `must not start with "hedvig", which is reserved by the cluster`},
+		{"vdisk01-hedvig", ""},
+	}
+
+	for _, tc := range cases {
+		_, es := validateVdiskName(tc.name, "name")
+		switch {
+		case tc.expected == "" && len(es) > 0:
+			t.Errorf("%q: unexpected errors: %v", tc.name, es)
+		case tc.expected != "" && len(es) == 0:
+			t.Errorf("%q: expected error %q, got none", tc.name, tc.expected)
+		case tc.expected != "" && !strings.Contains(es[0].Error(), tc.expected):
+			t.Errorf("%q: expected error %q, got %q", tc.name, tc.expected, es[0])
+		}
+	}
+}
+
+func TestUnitHedvigVdisk_nameReserved(t *testing.T) {
+	s := testUnitServer()
+	defer s.Close()
+
+	resource.UnitTest(t, resource.TestCase{
+		Providers: testAccProviders,
+		Steps: []resource.TestStep{
+			{
+				Config:      testUnitConfig(s, testUnitHedvigVdiskResourceConfig("hedvig-data", 9)),
+				PlanOnly:    true,
+				ExpectError: regexp.MustCompile(`"name" must not start with "hedvig", which is reserved by the cluster`),
+			},
+		},
+	})
+}
+
+func TestUnitHedvigVdisk_nameImported(t *testing.T) {
+	s := testUnitServer()
+	defer s.Close()
+
+	// Created outside of Terraform, with a name it would not accept.
+	s.VDisks["UI.Disk"] = &clienttest.VDisk{
+		AddVirtualDisk: client.AddVirtualDisk{
+			Name:              "UI.Disk",
+			Size:              client.Size{Unit: "GB", Value: 9},
+			DiskType:          "BLOCK",
+			Residence:         "HDD",
+			ReplicationFactor: 3,
+			BlockSize:         4096,
+		},
+	}
+
+	resource.UnitTest(t, resource.TestCase{
+		Providers: testAccProviders,
+		Steps: []resource.TestStep{
+			{
+				Config:        testUnitConfig(s, testUnitHedvigVdiskResourceConfig("UI.Disk", 9), `name_prefix = "team-"`),
+				ResourceName:  "hedvig_vdisk.test",
+				ImportState:   true,
+				ImportStateId: "UI.Disk",
+				ImportStateCheck: func(states []*terraform.InstanceState) error {
+					if len(states) != 1 || states[0].ID != "v2/vdisk/UI.Disk/BLOCK" {
+						return fmt.Errorf("unexpected import result: %#v", states)
+					}
+					return nil
+				},
+			},
+		},
+	})
+}
+
+func TestUnitHedvigVdisk_namePrefix(t *testing.T) {
+	s := testUnitServer()
+	defer s.Close()
+
+	resource.UnitTest(t, resource.TestCase{
+		Providers:    testAccProviders,
+		CheckDestroy: testUnitCheckHedvigVdiskDestroyed(s),
+		Steps: []resource.TestStep{
+			{
+				Config:      testUnitConfig(s, testUnitHedvigVdiskResourceConfig("unit-data", 9), `name_prefix = "team-"`),
+				PlanOnly:    true,
+				ExpectError: regexp.MustCompile(`name: "unit-data" must start with the provider's name_prefix "team-"`),
+			},
+			{
+				Config: testUnitConfig(s, testUnitHedvigVdiskResourceConfig("team-data", 9), `name_prefix = "team-"`),
+				Check:  resource.TestCheckResourceAttr("hedvig_vdisk.test", "name", "team-data"),
+			},
+		},
+	})
+
+	if s.Requests["AddVirtualDisk"] != 1 {
+		t.Fatalf("expected only the prefixed vdisk to be created, got %d AddVirtualDisk requests", s.Requests["AddVirtualDisk"])
+	}
+}
+
+func TestUnitHedvigVdisk_deletionProtection(t *testing.T) {
+	s := testUnitServer()
+	defer s.Close()
+
+	resource.UnitTest(t, resource.TestCase{
+		Providers:    testAccProviders,
+		CheckDestroy: testUnitCheckHedvigVdiskDestroyed(s),
+		Steps: []resource.TestStep{
+			{
+				Config: testUnitConfig(s, testUnitHedvigVdiskResourceConfig("unit-delete", 9, "deletion_protection = true", "force_detach = false")),
+			},
+			{
+				Config:      testUnitConfig(s, ""),
+				ExpectError: regexp.MustCompile(`Refusing to delete vdisk "unit-delete" while deletion_protection is set`),
+			},
+			{
+				Config: testUnitConfig(s, testUnitHedvigVdiskResourceConfig("unit-delete", 9, "deletion_protection = false", "force_detach = false")),
+				Check:  resource.TestCheckResourceAttr("hedvig_vdisk.test", "deletion_protection", "false"),
+			},
+		},
+	})
+}
+
+func TestUnitHedvigVdisk_attached(t *testing.T) {
+	s := testUnitServer()
+	defer s.Close()
+
+	attach := func() {
+		s.Lock()
+		defer s.Unlock()
+		vdisk := s.VDisks["unit-delete"]
+		vdisk.Luns = []string{"iscsi.example.com"}
+		vdisk.ACL["iscsi.example.com"] = []string{"192.168.1.10"}
+	}
+
+	resource.UnitTest(t, resource.TestCase{
+		Providers: testAccProviders,
+		CheckDestroy: resource.ComposeTestCheckFunc(
+			testUnitCheckHedvigVdiskDestroyed(s),
+			func(*terraform.State) error {
+				s.Lock()
+				defer s.Unlock()
+				if s.Requests["UnmapLun"] != 1 || s.Requests["RemoveACLAccess"] != 1 {
+					return fmt.Errorf("expected the vdisk to be detached before it was deleted, got %d UnmapLun and %d RemoveACLAccess requests",
+						s.Requests["UnmapLun"], s.Requests["RemoveACLAccess"])
+				}
+				return nil
+			},
+		),
+		Steps: []resource.TestStep{
+			{
+				Config: testUnitConfig(s, testUnitHedvigVdiskResourceConfig("unit-delete", 9, "deletion_protection = false", "force_detach = false")),
+			},
+			{
+				PreConfig:   attach,
+				Config:      testUnitConfig(s, ""),
+				ExpectError: regexp.MustCompile(`Refusing to delete vdisk "unit-delete" while it is attached \(LUNs: iscsi.example.com; NFS: none; ACL hosts: iscsi.example.com\)`),
+			},
+			{
+				Config: testUnitConfig(s, testUnitHedvigVdiskResourceConfig("unit-delete", 9, "deletion_protection = false", "force_detach = true")),
+				Check: func(*terraform.State) error {
+					if s.VDisk("unit-delete") == nil {
+						return errors.New("attached vdisk was deleted without force_detach")
+					}
+					return nil
+				},
+			},
+		},
+	})
+}
+
+func TestUnitHedvigVdisk_capacity(t *testing.T) {
+	s := testUnitServer()
+	defer s.Close()
+	s.Capacity["HDD"] = 100 << 30
+
+	resource.UnitTest(t, resource.TestCase{
+		Providers:    testAccProviders,
+		CheckDestroy: testUnitCheckHedvigVdiskDestroyed(s),
+		Steps: []resource.TestStep{
+			{
+				Config:      testUnitConfig(s, testUnitHedvigVdiskResourceConfig("unit-capacity", 31), `capacity_check = "error"`),
+				ExpectError: regexp.MustCompile(`size: vdisk "unit-capacity" needs 93.0 GB on the HDD tier \(31 GB with replicationfactor 3\), which leaves less than 10% of it free \(100.0 GB of 100.0 GB free\)`),
+			},
+			{
+				Config: testUnitConfig(s, testUnitHedvigVdiskResourceConfig("unit-capacity", 20), `capacity_check = "error"`),
+				Check:  testUnitCheckHedvigVdiskSize(s, "unit-capacity", 20),
+			},
+			{
+				// Growing by 11 GB takes 33 GB more, of the 40 GB left.
+				Config:      testUnitConfig(s, testUnitHedvigVdiskResourceConfig("unit-capacity", 31), `capacity_check = "error"`),
+				ExpectError: regexp.MustCompile(`needs 33.0 GB on the HDD tier`),
+			},
+			{
+				// Only logged, as the plan has no way to show a warning.
+				Config: testUnitConfig(s, testUnitHedvigVdiskResourceConfig("unit-capacity", 31), `capacity_check = "warn"`),
+				Check:  testUnitCheckHedvigVdiskSize(s, "unit-capacity", 31),
+			},
+			{
+				Config: testUnitConfig(s, testUnitHedvigVdiskResourceConfig("unit-capacity", 33), `capacity_check = "off"`),
+				Check:  testUnitCheckHedvigVdiskSize(s, "unit-capacity", 33),
+			},
+		},
+	})
+}
+
+func TestUnitHedvigVdisk_capacityClone(t *testing.T) {
+	s := testUnitServer()
+	defer s.Close()
+	s.Capacity["HDD"] = 100 << 30
+	s.VDisks["unit-capacity-source"] = &clienttest.VDisk{
+		AddVirtualDisk: client.AddVirtualDisk{
+			Name:              "unit-capacity-source",
+			Size:              client.Size{Unit: "GB", Value: 10},
+			DiskType:          "BLOCK",
+			Residence:         "HDD",
+			ReplicationFactor: 3,
+			BlockSize:         4096,
+		},
+	}
+
+	resource.UnitTest(t, resource.TestCase{
+		Providers: testAccProviders,
+		Steps: []resource.TestStep{
+			{
+				// The clone takes its final size, not that of its source.
+				Config:      testUnitConfig(s, testUnitHedvigVdiskResourceConfig("unit-capacity-clone", 21, `source_vdisk = "unit-capacity-source"`)),
+				PlanOnly:    true,
+				ExpectError: regexp.MustCompile(`needs 63.0 GB on the HDD tier \(21 GB with replicationfactor 3\)`),
+			},
+		},
+	})
+}
+
+func TestUnitHedvigVdisk_adoptExisting(t *testing.T) {
+	s := testUnitServer()
+	defer s.Close()
+
+	s.VDisks["unit-adopt"] = &clienttest.VDisk{
+		AddVirtualDisk: client.AddVirtualDisk{
+			Name:              "unit-adopt",
+			Size:              client.Size{Unit: "GB", Value: 12},
+			DiskType:          "BLOCK",
+			Residence:         "HDD",
+			ReplicationFactor: 3,
+			ReplicationPolicy: "Agnostic",
+			BlockSize:         4096,
+			Description:       "left over",
+		},
+		ACL: map[string][]string{},
+	}
+
+	resource.UnitTest(t, resource.TestCase{
+		Providers:    testAccProviders,
+		CheckDestroy: testUnitCheckHedvigVdiskDestroyed(s),
+		Steps: []resource.TestStep{
+			{
+				Config:      testUnitConfig(s, testUnitHedvigVdiskResourceConfig("unit-adopt", 12, `description = "left over"`, "adopt_existing = false")),
+				ExpectError: regexp.MustCompile(`A vdisk named "unit-adopt" already exists on the cluster; import it or set adopt_existing`),
+			},
+			{
+				Config:      testUnitConfig(s, testUnitHedvigVdiskResourceConfig("unit-adopt", 9, `description = "new"`, "adopt_existing = true")),
+				ExpectError: regexp.MustCompile(`(?s)with different settings:.*size: configured 9 GB, cluster has 12 GB.*description: configured "new", cluster has "left over"`),
+			},
+			{
+				Config: testUnitConfig(s, testUnitHedvigVdiskResourceConfig("unit-adopt", 12, `description = "left over"`, "adopt_existing = true")),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("hedvig_vdisk.test", "id", "v2/vdisk/unit-adopt/BLOCK"),
+					resource.TestCheckResourceAttr("hedvig_vdisk.test", "size", "12"),
+				),
+			},
+		},
+	})
+}
+
+func TestUnitHedvigVdisk_createCollision(t *testing.T) {
+	s := testUnitServer()
+	defer s.Close()
+
+	// Another disk of the name shows up while AddVirtualDisk fails at the
+	// proxy, so the create must not be taken for applied.
+	s.VDisks["unit-collision"] = &clienttest.VDisk{
+		AddVirtualDisk: client.AddVirtualDisk{
+			Name:              "unit-collision",
+			Size:              client.Size{Unit: "GB", Value: 12},
+			DiskType:          "BLOCK",
+			Residence:         "Flash",
+			ReplicationFactor: 3,
+			ReplicationPolicy: "Agnostic",
+			BlockSize:         4096,
+		},
+		ACL: map[string][]string{},
+	}
+	s.Unavailable["AddVirtualDisk"] = 1
+
+	resource.UnitTest(t, resource.TestCase{
+		Providers: testAccProviders,
+		Steps: []resource.TestStep{
+			{
+				Config:      testUnitConfig(s, testUnitHedvigVdiskResourceConfig("unit-collision", 12), "retry {\n    min_backoff = \"1ms\"\n  }"),
+				ExpectError: regexp.MustCompile(`Error creating vdisk "unit-collision": .*502`),
+			},
+		},
+	})
+
+	if n := s.Requests["AddVirtualDisk"]; n != 1 {
+		t.Fatalf("expected AddVirtualDisk not to be replayed, got %d calls", n)
+	}
+	if residence := s.VDisk("unit-collision").Residence; residence != "Flash" {
+		t.Fatalf("expected the existing disk to be left alone, got residence %q", residence)
+	}
+}
+
+// testUnitHedvigVdiskResourceConfig returns a BLOCK hedvig_vdisk named
+// test with the given further attributes.
+func testUnitHedvigVdiskResourceConfig(name string, size int, attributes ...string) string {
+	return fmt.Sprintf(`
+resource "hedvig_vdisk" "test" {
+  name = "%s"
+  size = %d
+  type = "BLOCK"
+%s}
+`, name, size, testUnitArguments(attributes))
+}
+
+func testUnitHedvigVdiskConfig(size int) string {
+	return fmt.Sprintf(`
+resource "hedvig_vdisk" "test" {
+  name = "unit-vdisk"
+  size = %d
+  type = "BLOCK"
+  description = "it's \"quoted\""
+}
+
+resource "hedvig_vdisk" "nfs" {
+  name = "unit-nfs"
+  size = 11
+  type = "NFS"
+  blocksize = "512"
+  clusteredfilesystem = true
+  description = "shared"
+}
+`, size)
+}
+
+func testUnitCheckHedvigVdiskSize(s *clienttest.Server, name string, size int) resource.TestCheckFunc {
+	return func(*terraform.State) error {
+		vdisk := s.VDisk(name)
+		if vdisk == nil {
+			return fmt.Errorf("vdisk %s not found on the cluster", name)
+		}
+		if vdisk.Size.Value != size {
+			return fmt.Errorf("expected vdisk %s to have size %d, got %d", name, size, vdisk.Size.Value)
+		}
+		return nil
+	}
+}
+
+func testUnitCheckHedvigVdiskDestroyed(s *clienttest.Server) resource.TestCheckFunc {
+	return func(*terraform.State) error {
+		s.Lock()
+		defer s.Unlock()
+
+		for name := range s.VDisks {
+			return fmt.Errorf("vdisk %s still exists", name)
+		}
+		return nil
+	}
+}
@@ -7,9 +7,13 @@ import (
 )
 
 func main() {
+	provider := hedvig.Provider()
+
 	plugin.Serve(&plugin.ServeOpts{
 		ProviderFunc: func() terraform.ResourceProvider {
-			return hedvig.Provider()
+			return provider
 		},
 	})
+
+	hedvig.Logout(provider)
 }